@@ -0,0 +1,225 @@
+// Package audit records mutating requests in the audit log. Handlers describe
+// what they changed with Annotate; the audit middleware adds the request
+// details and hands the entry to a background writer. Security-relevant changes,
+// such as role and permission changes, are written with Write in the transaction
+// that makes them instead, so they cannot be dropped.
+package audit
+
+import (
+	"encoding/json"
+	"final/models"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// queueSize is how many entries may wait for the writer before new ones are dropped
+	queueSize = 1024
+	// batchSize is the most entries written in one insert
+	batchSize = 100
+	// flushInterval is how long an entry may wait for a batch to fill
+	flushInterval = time.Second
+)
+
+const (
+	// eventKey is the gin context key holding the request's Event
+	eventKey = "audit_event"
+	// writtenKey is the gin context key set once Write stored the request's entry
+	writtenKey = "audit_written"
+)
+
+// Event describes the entity a request changed
+type Event struct {
+	Action     string
+	EntityType string
+	EntityID   string
+	// Before and After are snapshots of the entity; only changed fields are stored
+	Before interface{}
+	After  interface{}
+	// ActorID overrides the authenticated user, e.g. for logins
+	ActorID *uuid.UUID
+}
+
+// Annotate attaches an event to the request for the audit middleware to record
+func Annotate(c *gin.Context, event Event) {
+	c.Set(eventKey, event)
+}
+
+// FromContext returns the event attached to the request, if any
+func FromContext(c *gin.Context) (Event, bool) {
+	value, exists := c.Get(eventKey)
+	if !exists {
+		return Event{}, false
+	}
+	event, ok := value.(Event)
+	return event, ok
+}
+
+// NewEntry builds the audit entry for a request that responded with status. The
+// event, when given, names the entity the request changed.
+func NewEntry(c *gin.Context, status int, event *Event) models.AuditLog {
+	entry := models.AuditLog{
+		Action:     c.Request.Method + " " + c.FullPath(),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		StatusCode: status,
+		IPAddress:  c.ClientIP(),
+		RequestID:  c.GetString("request_id"),
+		Timestamp:  time.Now(),
+	}
+	if value, exists := c.Get("user_id"); exists {
+		if userID, err := uuid.Parse(fmt.Sprint(value)); err == nil {
+			entry.UserID = &userID
+		}
+	}
+
+	if event != nil {
+		entry.Action = event.Action
+		entry.EntityType = event.EntityType
+		entry.EntityID = event.EntityID
+		entry.Before, entry.After = Diff(event.Before, event.After)
+		if event.ActorID != nil {
+			entry.UserID = event.ActorID
+		}
+	}
+	return entry
+}
+
+// Write inserts the entry for a change in the transaction that makes it, so the
+// change is never committed without its entry. status is the response the handler
+// sends once the transaction commits. The audit middleware does not record the
+// request again.
+func Write(tx *gorm.DB, c *gin.Context, status int, event Event) error {
+	entry := NewEntry(c, status, &event)
+	if err := tx.Omit("User").Create(&entry).Error; err != nil {
+		return err
+	}
+	c.Set(writtenKey, true)
+	return nil
+}
+
+// Written reports whether Write stored an entry for the request. The entry is
+// only kept if the request's transaction committed, which its status tells.
+func Written(c *gin.Context) bool {
+	return c.GetBool(writtenKey)
+}
+
+var queue chan models.AuditLog
+
+// Start launches the background writer for request logging. Entries recorded
+// before Start are dropped.
+func Start(db *gorm.DB) {
+	queue = make(chan models.AuditLog, queueSize)
+	go write(db, queue)
+}
+
+// Record queues an entry without blocking; when the writer falls behind the entry is logged and dropped
+func Record(entry models.AuditLog) {
+	select {
+	case queue <- entry:
+	default:
+		log.Printf("Audit queue full, dropping entry: %s %s by %v", entry.Action, entry.EntityID, entry.UserID)
+	}
+}
+
+// write inserts queued entries in batches
+func write(db *gorm.DB, entries <-chan models.AuditLog) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditLog, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := db.Omit("User").CreateInBatches(batch, batchSize).Error; err != nil {
+			log.Printf("Failed to write %d audit entries: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry := <-entries:
+			batch = append(batch, entry)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Diff returns the fields that differ between two snapshots. Nested objects and
+// lists of objects, which are associations rather than the entity's own columns,
+// are skipped.
+// A nil snapshot, as for creates and deletes, keeps every field of the other.
+func Diff(before, after interface{}) (models.JSONMap, models.JSONMap) {
+	b, a := snapshot(before), snapshot(after)
+	if b == nil || a == nil {
+		return b, a
+	}
+
+	changedBefore, changedAfter := models.JSONMap{}, models.JSONMap{}
+	for key, value := range a {
+		if !reflect.DeepEqual(b[key], value) {
+			changedBefore[key] = b[key]
+			changedAfter[key] = value
+		}
+	}
+	for key, value := range b {
+		if _, ok := a[key]; !ok {
+			changedBefore[key] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// snapshot converts a value to its JSON fields, dropping nested objects and lists of
+// objects. Money values marshal to objects too but are the entity's own columns, so
+// they are kept.
+func snapshot(value interface{}) models.JSONMap {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var fields models.JSONMap
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	for key, field := range fields {
+		switch field := field.(type) {
+		case map[string]interface{}:
+			if !isMoney(field) {
+				delete(fields, key)
+			}
+		case []interface{}:
+			if len(field) > 0 {
+				if _, isObject := field[0].(map[string]interface{}); isObject {
+					delete(fields, key)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// isMoney reports whether a nested object is a marshalled models.Money
+func isMoney(field map[string]interface{}) bool {
+	if len(field) != 2 {
+		return false
+	}
+	_, hasAmount := field["amount"].(string)
+	_, hasCurrency := field["currency"].(string)
+	return hasAmount && hasCurrency
+}
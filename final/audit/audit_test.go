@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"final/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestDiffKeepsOnlyChangedFields(t *testing.T) {
+	type role struct {
+		Name        string
+		Description string
+		Permissions []struct{ Name string }
+	}
+	before := role{Name: "editor", Description: "Edits products", Permissions: []struct{ Name string }{{"a"}}}
+	after := role{Name: "writer", Description: "Edits products"}
+
+	b, a := Diff(before, after)
+	if len(b) != 1 || b["Name"] != "editor" {
+		t.Errorf("before = %v, want only Name: editor", b)
+	}
+	if len(a) != 1 || a["Name"] != "writer" {
+		t.Errorf("after = %v, want only Name: writer", a)
+	}
+}
+
+func TestDiffKeepsMoneyFields(t *testing.T) {
+	before := models.Product{Name: "Lamp", Price: models.NewMoney(1000), Category: models.Category{Name: "Home"}}
+	after := models.Product{Name: "Lamp", Price: models.NewMoney(500), Category: models.Category{Name: "Garden"}}
+
+	b, a := Diff(before, after)
+	if len(b) != 1 || len(a) != 1 {
+		t.Fatalf("before = %v, after = %v, want only Price", b, a)
+	}
+	if price, ok := b["Price"].(map[string]interface{}); !ok || price["amount"] != "10.00" {
+		t.Errorf("before Price = %v, want amount 10.00", b["Price"])
+	}
+	if price, ok := a["Price"].(map[string]interface{}); !ok || price["amount"] != "5.00" {
+		t.Errorf("after Price = %v, want amount 5.00", a["Price"])
+	}
+}
+
+func TestDiffKeepsEverythingForCreates(t *testing.T) {
+	b, a := Diff(nil, gin.H{"role": "admin"})
+	if b != nil {
+		t.Errorf("before = %v, want nil", b)
+	}
+	if a["role"] != "admin" {
+		t.Errorf("after = %v, want role: admin", a)
+	}
+}
+
+func TestNewEntryDescribesRequestAndEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPut, "/users/1/role", nil)
+	userID, actorID := uuid.New(), uuid.New()
+	c.Set("user_id", userID.String())
+	c.Set("request_id", "req-1")
+
+	entry := NewEntry(c, http.StatusOK, nil)
+	if entry.Method != http.MethodPut || entry.Path != "/users/1/role" || entry.StatusCode != http.StatusOK {
+		t.Errorf("entry = %+v, want the request's method, path and status", entry)
+	}
+	if entry.UserID == nil || *entry.UserID != userID || entry.RequestID != "req-1" {
+		t.Errorf("entry user = %v, request = %q, want %v and req-1", entry.UserID, entry.RequestID, userID)
+	}
+
+	entry = NewEntry(c, http.StatusOK, &Event{
+		Action:     "user.role_change",
+		EntityType: "user",
+		EntityID:   "1",
+		Before:     gin.H{"role": "user"},
+		After:      gin.H{"role": "admin"},
+		ActorID:    &actorID,
+	})
+	if entry.Action != "user.role_change" || entry.EntityType != "user" || entry.EntityID != "1" {
+		t.Errorf("entry = %+v, want the event's action and entity", entry)
+	}
+	if entry.After["role"] != "admin" || *entry.UserID != actorID {
+		t.Errorf("entry after = %v, user = %v, want role admin by %v", entry.After, entry.UserID, actorID)
+	}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"final/config"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Cache stores short-lived values, such as rendered API responses, under string keys.
+// Keys can be tagged so that everything derived from an entity is dropped together.
+type Cache interface {
+	// Get returns the value under key; ok is false when it is missing or expired
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key for ttl and associates the key with tags
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// InvalidateTags removes every key associated with any of the tags
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// Purger is implemented by backends that must remove expired entries themselves
+type Purger interface {
+	// PurgeExpired deletes expired entries and returns how many were removed
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// ProductsTag is attached to every cached product response, so any change to
+// products, their stock, images or reviews can drop them all at once
+const ProductsTag = "products"
+
+// Default is the cache used by the API; nil when caching is disabled
+var Default Cache
+
+// TTL is how long cached responses are kept, from CACHE_TTL
+var TTL = time.Minute
+
+// Init configures Default from the CACHE_BACKEND environment variable
+// ("memory", "postgres", "redis" or "none")
+func Init() error {
+	if raw := os.Getenv("CACHE_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid CACHE_TTL %q", raw)
+		}
+		TTL = ttl
+	}
+
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "", "memory":
+		size, err := strconv.Atoi(getenv("CACHE_MEMORY_SIZE", "1000"))
+		if err != nil || size < 1 {
+			return fmt.Errorf("invalid CACHE_MEMORY_SIZE %q", os.Getenv("CACHE_MEMORY_SIZE"))
+		}
+		Default = NewMemoryCache(size)
+	case "postgres":
+		Default = NewPostgresCache(config.DB)
+	case "redis":
+		db, err := strconv.Atoi(getenv("CACHE_REDIS_DB", "0"))
+		if err != nil {
+			return fmt.Errorf("invalid CACHE_REDIS_DB %q", os.Getenv("CACHE_REDIS_DB"))
+		}
+		Default = NewRedisCache(RedisConfig{
+			Addr:     getenv("CACHE_REDIS_ADDR", "localhost:6379"),
+			Password: os.Getenv("CACHE_REDIS_PASSWORD"),
+			DB:       db,
+			Prefix:   getenv("CACHE_REDIS_PREFIX", "final:"),
+		})
+	case "none":
+		Default = nil
+	default:
+		return fmt.Errorf("unknown cache backend %q", backend)
+	}
+	return nil
+}
+
+// StartPurger removes expired entries from the default cache every interval, for
+// backends that do not expire entries on their own
+func StartPurger(interval time.Duration) {
+	purger, ok := Default.(Purger)
+	if !ok {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			removed, err := purger.PurgeExpired(context.Background())
+			if err != nil {
+				log.Printf("Failed to purge expired cache entries: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("Purged %d expired cache entries", removed)
+			}
+		}
+	}()
+}
+
+// getenv returns the environment variable or a fallback when it is unset
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
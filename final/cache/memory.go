@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process LRU cache. Each API instance has its own copy, so it
+// suits single-instance deployments and development.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	// order holds entries from most to least recently used
+	order   *list.List
+	entries map[string]*list.Element
+	// tags maps a tag to the keys associated with it
+	tags map[string]map[string]struct{}
+}
+
+// memoryEntry is one cached value
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	tags      []string
+}
+
+// NewMemoryCache creates an LRU cache holding at most capacity entries
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if time.Now().After(entry.expiresAt) {
+		m.remove(element)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+
+	entry := &memoryEntry{key: key, value: value, expiresAt: time.Now().Add(ttl), tags: tags}
+	m.entries[key] = m.order.PushFront(entry)
+	for _, tag := range tags {
+		if m.tags[tag] == nil {
+			m.tags[tag] = make(map[string]struct{})
+		}
+		m.tags[tag][key] = struct{}{}
+	}
+
+	// Evict the least recently used entries beyond capacity
+	for m.order.Len() > m.capacity {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+func (m *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			if element, ok := m.entries[key]; ok {
+				m.remove(element)
+			}
+		}
+		delete(m.tags, tag)
+	}
+	return nil
+}
+
+func (m *MemoryCache) PurgeExpired(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var removed int64
+	now := time.Now()
+	for element := m.order.Back(); element != nil; {
+		previous := element.Prev()
+		if now.After(element.Value.(*memoryEntry).expiresAt) {
+			m.remove(element)
+			removed++
+		}
+		element = previous
+	}
+	return removed, nil
+}
+
+// remove drops an entry and its tag associations; the caller holds the lock
+func (m *MemoryCache) remove(element *list.Element) {
+	entry := element.Value.(*memoryEntry)
+	m.order.Remove(element)
+	delete(m.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(m.tags[tag], entry.key)
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"final/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresCache keeps entries in the caches table so every API instance shares them.
+// Expired rows are ignored on read and removed by PurgeExpired.
+type PostgresCache struct {
+	db *gorm.DB
+}
+
+// NewPostgresCache creates a cache backed by the caches and cache_tags tables
+func NewPostgresCache(db *gorm.DB) *PostgresCache {
+	return &PostgresCache{db: db}
+}
+
+func (p *PostgresCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var entry models.Cache
+	err := p.db.WithContext(ctx).
+		Where("cache_key = ? AND expiration_time > ?", key, time.Now()).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(entry.CacheValue), true, nil
+}
+
+func (p *PostgresCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	return p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entry := models.Cache{CacheKey: key, CacheValue: string(value), ExpirationTime: time.Now().Add(ttl)}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "cache_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"cache_value", "expiration_time"}),
+		}).Create(&entry).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("cache_key = ?", key).Delete(&models.CacheTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		rows := make([]models.CacheTag, 0, len(tags))
+		for _, tag := range tags {
+			rows = append(rows, models.CacheTag{Tag: tag, CacheKey: key})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+	})
+}
+
+func (p *PostgresCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	// Deleting the entries cascades to their tags
+	return p.db.WithContext(ctx).
+		Where("cache_key IN (?)", p.db.Model(&models.CacheTag{}).Select("cache_key").Where("tag IN ?", tags)).
+		Delete(&models.Cache{}).Error
+}
+
+func (p *PostgresCache) PurgeExpired(ctx context.Context) (int64, error) {
+	result := p.db.WithContext(ctx).Where("expiration_time <= ?", time.Now()).Delete(&models.Cache{})
+	return result.RowsAffected, result.Error
+}
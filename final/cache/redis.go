@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// redisTimeout bounds each round trip when the context has no deadline
+	redisTimeout = 2 * time.Second
+	// redisMaxIdle is how many idle connections are kept for reuse
+	redisMaxIdle = 8
+)
+
+// RedisConfig holds the connection settings for a Redis-compatible server
+type RedisConfig struct {
+	// Addr is the server's host:port
+	Addr     string
+	Password string
+	DB       int
+	// Prefix is prepended to every key so several applications can share a server
+	Prefix string
+}
+
+// RedisCache stores entries in Redis or a server speaking its protocol, such as
+// Valkey or KeyDB. Entries expire on the server; tags are kept as sets of keys.
+type RedisCache struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+// redisConn is one connection to the server
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedisCache creates a cache that connects to the server on first use
+func NewRedisCache(config RedisConfig) *RedisCache {
+	return &RedisCache{config: config, idle: make(chan *redisConn, redisMaxIdle)}
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	replies, err := r.do(ctx, []string{"GET", r.config.Prefix + key})
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := replies[0].([]byte)
+	return value, ok, nil
+}
+
+// Set stores the entry and adds it to each tag's set. Tag sets are given the
+// entry's TTL, so they live as long as the newest entry added to them.
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	millis := strconv.FormatInt(ttl.Milliseconds(), 10)
+	commands := [][]string{{"SET", r.config.Prefix + key, string(value), "PX", millis}}
+	for _, tag := range tags {
+		tagKey := r.tagKey(tag)
+		commands = append(commands,
+			[]string{"SADD", tagKey, key},
+			[]string{"PEXPIRE", tagKey, millis},
+		)
+	}
+	_, err := r.do(ctx, commands...)
+	return err
+}
+
+func (r *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := r.tagKey(tag)
+		replies, err := r.do(ctx, []string{"SMEMBERS", tagKey})
+		if err != nil {
+			return err
+		}
+		members, _ := replies[0].([]interface{})
+
+		del := []string{"DEL", tagKey}
+		for _, member := range members {
+			if key, ok := member.([]byte); ok {
+				del = append(del, r.config.Prefix+string(key))
+			}
+		}
+		if _, err := r.do(ctx, del); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tagKey is the key of the set holding a tag's cache keys
+func (r *RedisCache) tagKey(tag string) string {
+	return r.config.Prefix + "tag:" + tag
+}
+
+// do pipelines the commands on one connection and returns their replies in order.
+// A command that fails with an error reply fails the whole call.
+func (r *RedisCache) do(ctx context.Context, commands ...[]string) ([]interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	conn.conn.SetDeadline(deadline)
+
+	replies, err := conn.roundTrip(commands)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection is in an unknown state after an I/O or protocol error
+		conn.conn.Close()
+		return nil, err
+	}
+	r.put(conn)
+	return replies, err
+}
+
+// get returns an idle connection or dials a new one
+func (r *RedisCache) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: redisTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.config.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	var setup [][]string
+	if r.config.Password != "" {
+		setup = append(setup, []string{"AUTH", r.config.Password})
+	}
+	if r.config.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.config.DB)})
+	}
+	if len(setup) > 0 {
+		netConn.SetDeadline(time.Now().Add(redisTimeout))
+		if _, err := conn.roundTrip(setup); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put returns a connection to the idle pool, closing it when the pool is full
+func (r *RedisCache) put(conn *redisConn) {
+	select {
+	case r.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// roundTrip writes the commands and reads one reply per command
+func (c *redisConn) roundTrip(commands [][]string) ([]interface{}, error) {
+	writer := bufio.NewWriter(c.conn)
+	for _, args := range commands {
+		fmt.Fprintf(writer, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Read every reply even after an error reply so the connection stays in sync
+	replies := make([]interface{}, len(commands))
+	var firstErr error
+	for i := range commands {
+		reply, err := c.readReply()
+		var replyErr redisError
+		if err != nil && !errors.As(err, &replyErr) {
+			return nil, err
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		replies[i] = reply
+	}
+	return replies, firstErr
+}
+
+// readReply parses one RESP reply: strings and bulk strings become []byte,
+// integers int64, arrays []interface{} and nil replies nil
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return []byte(payload), nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			// Nested error replies only occur inside MULTI/EXEC, which is not used
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", kind)
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// replyConn parses replies from a fixed server response
+func replyConn(response string) *redisConn {
+	return &redisConn{reader: bufio.NewReader(strings.NewReader(response))}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		response string
+		want     interface{}
+	}{
+		{"+OK\r\n", []byte("OK")},
+		{":42\r\n", int64(42)},
+		{":-1\r\n", int64(-1)},
+		{"$5\r\nhello\r\n", []byte("hello")},
+		{"$0\r\n\r\n", []byte{}},
+		{"$7\r\nab\r\ncd\n\r\n", []byte("ab\r\ncd\n")},
+		{"$-1\r\n", nil},
+		{"*-1\r\n", nil},
+		{"*0\r\n", []interface{}{}},
+		{"*3\r\n$1\r\na\r\n:2\r\n*1\r\n$-1\r\n", []interface{}{[]byte("a"), int64(2), []interface{}{nil}}},
+	}
+	for _, tt := range tests {
+		got, err := replyConn(tt.response).readReply()
+		if err != nil {
+			t.Errorf("readReply(%q) failed: %v", tt.response, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readReply(%q) = %#v, want %#v", tt.response, got, tt.want)
+		}
+	}
+}
+
+func TestReadReplyErrors(t *testing.T) {
+	_, err := replyConn("-WRONGTYPE Operation against a key\r\n").readReply()
+	var replyErr redisError
+	if !errors.As(err, &replyErr) || replyErr != "WRONGTYPE Operation against a key" {
+		t.Errorf("error reply = %v, want a redisError", err)
+	}
+
+	for _, response := range []string{"+OK\n", "!3\r\nabc\r\n", "$5\r\nab", ":x\r\n", ""} {
+		_, err := replyConn(response).readReply()
+		if err == nil || errors.As(err, &replyErr) {
+			t.Errorf("readReply(%q) = %v, want a protocol error", response, err)
+		}
+	}
+}
+
+func TestRoundTripReadsEveryReplyAfterAnError(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	commands := make(chan []interface{}, 3)
+	go func() {
+		defer server.Close()
+		conn := &redisConn{conn: server, reader: bufio.NewReader(server)}
+		for i := 0; i < 3; i++ {
+			command, err := conn.readReply()
+			if err != nil {
+				return
+			}
+			commands <- command.([]interface{})
+		}
+		server.Write([]byte("+OK\r\n-ERR no such key\r\n:1\r\n"))
+	}()
+
+	conn := &redisConn{conn: client, reader: bufio.NewReader(client)}
+	replies, err := conn.roundTrip([][]string{{"SET", "k", "a b\r\n"}, {"RENAME", "x", "y"}, {"DEL", "k"}})
+	if err == nil || err.Error() != "redis: ERR no such key" {
+		t.Errorf("roundTrip error = %v, want the error reply", err)
+	}
+	if want := []interface{}{[]byte("OK"), nil, int64(1)}; !reflect.DeepEqual(replies, want) {
+		t.Errorf("replies = %#v, want %#v", replies, want)
+	}
+
+	first := <-commands
+	if want := []interface{}{[]byte("SET"), []byte("k"), []byte("a b\r\n")}; !reflect.DeepEqual(first, want) {
+		t.Errorf("sent %q, want %q", first, want)
+	}
+}
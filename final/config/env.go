@@ -0,0 +1,26 @@
+package config
+
+import (
+	"log"
+	"os"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+var envOnce sync.Once
+
+// loadEnv loads environment variables from the .env file once
+func loadEnv() {
+	envOnce.Do(func() {
+		if err := godotenv.Load(); err != nil {
+			log.Println("No .env file found, using system environment variables")
+		}
+	})
+}
+
+// IsDevelopment reports whether the server runs in development mode (APP_ENV=development)
+func IsDevelopment() bool {
+	loadEnv()
+	return os.Getenv("APP_ENV") == "development"
+}
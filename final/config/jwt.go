@@ -0,0 +1,207 @@
+package config
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Supported JWT signing algorithms
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// JWTKey is a key that can verify, and optionally sign, access tokens
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	// Secret is set for HS256 keys
+	Secret []byte
+	// PrivateKey is set for RS256 and EdDSA keys loaded from a private key file
+	PrivateKey crypto.Signer
+	// PublicKey is set for RS256 and EdDSA keys
+	PublicKey crypto.PublicKey
+}
+
+// JWTConfig holds the keys and claims used for access tokens
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	// SigningKey signs new tokens; every key in Keys is accepted when verifying
+	SigningKey *JWTKey
+	Keys       map[string]*JWTKey
+}
+
+// JWT is the access token configuration loaded by LoadJWTConfig
+var JWT *JWTConfig
+
+// LoadJWTConfig reads the access token configuration from the environment:
+//
+//	JWT_ISSUER, JWT_AUDIENCE   expected iss and aud claims
+//	JWT_KEYS                   comma-separated kid=ALG:value entries, where value is the
+//	                           secret for HS256 or a PEM file path for RS256 and EdDSA
+//	JWT_SIGNING_KEY_ID         kid of the key used to sign new tokens (defaults to the first)
+//	JWT_SECRET                 shorthand for a single HS256 key when JWT_KEYS is unset
+//
+// Keys that are kept in JWT_KEYS but no longer used for signing still verify
+// tokens, which lets keys be rotated without logging everyone out. Without any
+// key the server refuses to start, except in development mode (APP_ENV=development)
+// where a random key is generated for the life of the process.
+func LoadJWTConfig() error {
+	loadEnv()
+
+	cfg := &JWTConfig{
+		Issuer:   getenv("JWT_ISSUER", "final"),
+		Audience: getenv("JWT_AUDIENCE", "final-api"),
+		Keys:     make(map[string]*JWTKey),
+	}
+
+	entries := os.Getenv("JWT_KEYS")
+	if entries == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			if !IsDevelopment() {
+				return errors.New("JWT_KEYS or JWT_SECRET must be set")
+			}
+			var err error
+			if secret, err = randomSecret(); err != nil {
+				return err
+			}
+			log.Println("JWT_KEYS is not set; signing with a random development key, tokens will not survive a restart")
+		}
+		entries = "default=" + JWTAlgHS256 + ":" + secret
+	}
+
+	var firstID string
+	for _, entry := range strings.Split(entries, ",") {
+		key, err := parseJWTKey(strings.TrimSpace(entry))
+		if err != nil {
+			return err
+		}
+		if _, exists := cfg.Keys[key.ID]; exists {
+			return fmt.Errorf("duplicate JWT key id %q", key.ID)
+		}
+		cfg.Keys[key.ID] = key
+		if firstID == "" {
+			firstID = key.ID
+		}
+	}
+
+	signingID := getenv("JWT_SIGNING_KEY_ID", firstID)
+	signingKey, ok := cfg.Keys[signingID]
+	if !ok {
+		return fmt.Errorf("JWT signing key %q is not listed in JWT_KEYS", signingID)
+	}
+	if signingKey.Algorithm != JWTAlgHS256 && signingKey.PrivateKey == nil {
+		return fmt.Errorf("JWT signing key %q has no private key", signingID)
+	}
+	cfg.SigningKey = signingKey
+
+	JWT = cfg
+	return nil
+}
+
+// randomSecret returns a fresh HS256 secret for development mode
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// parseJWTKey parses one kid=ALG:value entry of JWT_KEYS
+func parseJWTKey(entry string) (*JWTKey, error) {
+	id, rest, ok := strings.Cut(entry, "=")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid JWT key entry %q, expected kid=ALG:value", entry)
+	}
+	algorithm, value, ok := strings.Cut(rest, ":")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("invalid JWT key entry for %q, expected kid=ALG:value", id)
+	}
+
+	key := &JWTKey{ID: id, Algorithm: algorithm}
+	switch algorithm {
+	case JWTAlgHS256:
+		if len(value) < 32 {
+			return nil, fmt.Errorf("HS256 secret for JWT key %q must be at least 32 characters", id)
+		}
+		key.Secret = []byte(value)
+		return key, nil
+	case JWTAlgRS256, JWTAlgEdDSA:
+		if err := loadPEMKey(key, value); err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", id, err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q for JWT key %q", algorithm, id)
+	}
+}
+
+// loadPEMKey reads a private or public key from a PEM file and checks it matches the key's algorithm
+func loadPEMKey(key *JWTKey, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("%s does not contain a PEM block", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.PrivateKey, key.PublicKey = k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.PublicKey = k
+	case ed25519.PrivateKey:
+		key.PrivateKey, key.PublicKey = k, k.Public()
+	case ed25519.PublicKey:
+		key.PublicKey = k
+	default:
+		return fmt.Errorf("unsupported key type %T in %s", parsed, path)
+	}
+
+	_, isRSA := key.PublicKey.(*rsa.PublicKey)
+	if (key.Algorithm == JWTAlgRS256) != isRSA {
+		return fmt.Errorf("%s does not hold a key for %s", path, key.Algorithm)
+	}
+	return nil
+}
+
+// getenv returns the environment variable or a fallback when it is unset
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
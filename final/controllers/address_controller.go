@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/models"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// zipCodePatterns holds postal code formats by ISO 3166-1 alpha-2 country code
+var zipCodePatterns = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"KZ": regexp.MustCompile(`^\d{6}$`),
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+}
+
+// genericZipCodePattern is used for countries without a specific format
+var genericZipCodePattern = regexp.MustCompile(`^[A-Z\d][A-Z\d -]{1,8}[A-Z\d]$`)
+
+var (
+	// errAddressRequired is returned when checkout has no shipping address to use
+	errAddressRequired = errors.New("a shipping address is required")
+	// errAddressNotFound is returned when checkout names an address the user does not have
+	errAddressNotFound = errors.New("address not found")
+)
+
+// addressInput is the payload for creating or updating an address
+type addressInput struct {
+	Label             string `json:"label" binding:"max=50"`
+	RecipientName     string `json:"recipient_name" binding:"required,max=100"`
+	Street            string `json:"street" binding:"required,max=255"`
+	City              string `json:"city" binding:"required,max=100"`
+	State             string `json:"state" binding:"max=100"`
+	ZipCode           string `json:"zip_code" binding:"required,max=20"`
+	Country           string `json:"country" binding:"required,len=2,alpha"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+}
+
+// normalize upper-cases codes and validates the zip code for the country
+func (input *addressInput) normalize() error {
+	input.Country = strings.ToUpper(input.Country)
+	input.ZipCode = strings.ToUpper(strings.TrimSpace(input.ZipCode))
+
+	pattern, ok := zipCodePatterns[input.Country]
+	if !ok {
+		pattern = genericZipCodePattern
+	}
+	if !pattern.MatchString(input.ZipCode) {
+		return fmt.Errorf("invalid zip code %q for country %s", input.ZipCode, input.Country)
+	}
+	return nil
+}
+
+// apply copies the input onto an address
+func (input addressInput) apply(address *models.UserAddress) {
+	address.Label = input.Label
+	address.RecipientName = input.RecipientName
+	address.Street = input.Street
+	address.City = input.City
+	address.State = input.State
+	address.ZipCode = input.ZipCode
+	address.Country = input.Country
+	address.IsDefaultShipping = input.IsDefaultShipping
+	address.IsDefaultBilling = input.IsDefaultBilling
+}
+
+// snapshotAddress copies an address for storing on an order
+func snapshotAddress(address models.UserAddress) models.AddressSnapshot {
+	return models.AddressSnapshot{
+		RecipientName: address.RecipientName,
+		Street:        address.Street,
+		City:          address.City,
+		State:         address.State,
+		ZipCode:       address.ZipCode,
+		Country:       address.Country,
+	}
+}
+
+// saveAddress stores an address and keeps at most one default shipping and billing address per user
+func saveAddress(tx *gorm.DB, address *models.UserAddress) error {
+	// The first address becomes the default for both
+	var count int64
+	if err := tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND address_id <> ?", address.UserID, address.AddressID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		address.IsDefaultShipping = true
+		address.IsDefaultBilling = true
+	}
+
+	if address.IsDefaultShipping {
+		if err := tx.Model(&models.UserAddress{}).
+			Where("user_id = ? AND address_id <> ?", address.UserID, address.AddressID).
+			Update("is_default_shipping", false).Error; err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultBilling {
+		if err := tx.Model(&models.UserAddress{}).
+			Where("user_id = ? AND address_id <> ?", address.UserID, address.AddressID).
+			Update("is_default_billing", false).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Save(address).Error; err != nil {
+		return err
+	}
+	// Unsetting a default hands it to another address; reload in case it came back here
+	if err := promoteDefaultAddresses(tx, address.UserID, address.AddressID); err != nil {
+		return err
+	}
+	return tx.First(address, "address_id = ?", address.AddressID).Error
+}
+
+// promoteDefaultAddresses gives each default flag that none of the user's addresses holds
+// to their most recent address, preferring one other than avoidID, so that checkout
+// always finds a default while the user has any address
+func promoteDefaultAddresses(tx *gorm.DB, userID, avoidID uuid.UUID) error {
+	for _, column := range []string{"is_default_shipping", "is_default_billing"} {
+		var count int64
+		if err := tx.Model(&models.UserAddress{}).
+			Where("user_id = ? AND "+column+" = true", userID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		var address models.UserAddress
+		err := tx.Where("user_id = ?", userID).
+			Order(clause.Expr{SQL: "address_id = ?, created_at DESC", Vars: []interface{}{avoidID}}).
+			First(&address).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&address).Update(column, true).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findCheckoutAddress returns the address with the given ID, or the user's default for the flag column
+func findCheckoutAddress(tx *gorm.DB, userID uuid.UUID, addressID *uuid.UUID, defaultColumn string) (*models.UserAddress, error) {
+	var address models.UserAddress
+	query := tx.Where("user_id = ?", userID)
+	if addressID != nil {
+		query = query.Where("address_id = ?", *addressID)
+	} else {
+		query = query.Where(defaultColumn + " = true")
+	}
+
+	err := query.First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if addressID != nil {
+			return nil, errAddressNotFound
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// List the current user's addresses
+func GetAddresses(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var addresses []models.UserAddress
+	if err := config.DB.Where("user_id = ?", userID).Order("created_at").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addresses"})
+		return
+	}
+	c.JSON(http.StatusOK, addresses)
+}
+
+// Add an address to the current user's address book
+func CreateAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input addressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	address := models.UserAddress{AddressID: uuid.New(), UserID: userID}
+	input.apply(&address)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return saveAddress(tx, &address)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save address"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// Update one of the current user's addresses
+func UpdateAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var address models.UserAddress
+	if err := config.DB.Where("address_id = ? AND user_id = ?", c.Param("id"), userID).First(&address).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	var input addressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.apply(&address)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return saveAddress(tx, &address)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// Delete one of the current user's addresses
+func DeleteAddress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("address_id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.UserAddress{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAddressNotFound
+		}
+		return promoteDefaultAddresses(tx, userID, uuid.Nil)
+	})
+	if errors.Is(err, errAddressNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted"})
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"final/models"
+	"final/testdb"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAddressInputNormalize(t *testing.T) {
+	tests := []struct {
+		country, zip string
+		wantZip      string
+		valid        bool
+	}{
+		{"us", "12345", "12345", true},
+		{"US", " 12345-6789 ", "12345-6789", true},
+		{"US", "1234", "", false},
+		{"US", "12345-67", "", false},
+		{"ca", "k1a 0b1", "K1A 0B1", true},
+		{"CA", "K1A0B1", "K1A0B1", true},
+		{"CA", "123456", "", false},
+		{"GB", "sw1a 1aa", "SW1A 1AA", true},
+		{"GB", "M1 1AE", "M1 1AE", true},
+		{"DE", "10115", "10115", true},
+		{"DE", "1011", "", false},
+		{"KZ", "050000", "050000", true},
+		{"JP", "100-0001", "100-0001", true},
+		{"JP", "1000001", "1000001", true},
+		{"AU", "2000", "2000", true},
+		{"AU", "20000", "", false},
+		// Countries without a specific format only get a sanity check
+		{"NL", "1012 ab", "1012 AB", true},
+		{"NL", "1", "", false},
+		{"NL", "12#45", "", false},
+	}
+	for _, tt := range tests {
+		input := addressInput{Country: tt.country, ZipCode: tt.zip}
+		err := input.normalize()
+		if (err == nil) != tt.valid {
+			t.Errorf("normalize(%s, %q) error = %v, want valid=%v", tt.country, tt.zip, err, tt.valid)
+			continue
+		}
+		if tt.valid && input.ZipCode != tt.wantZip {
+			t.Errorf("normalize(%s, %q) zip = %q, want %q", tt.country, tt.zip, input.ZipCode, tt.wantZip)
+		}
+	}
+}
+
+func TestAddressInputNormalizeUpperCasesCountry(t *testing.T) {
+	input := addressInput{Country: "us", ZipCode: "12345"}
+	if err := input.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if input.Country != "US" {
+		t.Errorf("country = %q, want US", input.Country)
+	}
+}
+
+func TestDefaultAddressMovesWhenUnsetOrDeleted(t *testing.T) {
+	tx := testdb.Open(t)
+	user := testdb.User(t, tx, models.RoleUser)
+
+	addresses := make([]models.UserAddress, 2)
+	for i := range addresses {
+		addresses[i] = models.UserAddress{AddressID: uuid.New(), UserID: user.UserID, RecipientName: "Test", Street: "1 Main St", City: "Springfield", ZipCode: "12345", Country: "US"}
+		if err := saveAddress(tx, &addresses[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, second := &addresses[0], &addresses[1]
+
+	// Unsetting the first address's defaults hands them to the other address
+	first.IsDefaultShipping, first.IsDefaultBilling = false, false
+	if err := saveAddress(tx, first); err != nil {
+		t.Fatal(err)
+	}
+	assertDefaults := func(want uuid.UUID) {
+		t.Helper()
+		for _, column := range []string{"is_default_shipping", "is_default_billing"} {
+			address, err := findCheckoutAddress(tx, user.UserID, nil, column)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if address == nil || address.AddressID != want {
+				t.Errorf("%s address = %v, want %s", column, address, want)
+			}
+		}
+	}
+	assertDefaults(second.AddressID)
+
+	// Deleting the default hands it back to the remaining address
+	if err := tx.Delete(second).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := promoteDefaultAddresses(tx, user.UserID, uuid.Nil); err != nil {
+		t.Fatal(err)
+	}
+	assertDefaults(first.AddressID)
+}
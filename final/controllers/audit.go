@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"final/audit"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit describes the entity a request changed; the audit middleware writes it
+// once the handler returns. before is nil for creates and after is nil for deletes.
+func recordAudit(c *gin.Context, action, entityType string, entityID interface{}, before, after interface{}) {
+	audit.Annotate(c, audit.Event{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Before:     before,
+		After:      after,
+	})
+}
+
+// writeAudit writes the audit entry for a security-relevant change, such as a role or
+// permission change, in the transaction that makes it. status is the response the
+// handler sends once the transaction commits.
+func writeAudit(tx *gorm.DB, c *gin.Context, status int, action, entityType string, entityID interface{}, before, after interface{}) error {
+	return audit.Write(tx, c, status, audit.Event{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Before:     before,
+		After:      after,
+	})
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"final/config"
+	"final/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// List audit log entries, newest first, filtered by user_id, entity_type, entity_id,
+// action and a from/to time range (RFC 3339)
+func GetAuditLogs(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Model(&models.AuditLog{})
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a UUID"})
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	for param, condition := range map[string]string{"from": "timestamp >= ?", "to": "timestamp < ?"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+			return
+		}
+		query = query.Where(condition, at)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	var entries []models.AuditLog
+	if err := query.Order("timestamp DESC").
+		Offset(page.Offset()).
+		Limit(page.PageSize).
+		Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      entries,
+		"page":      page.Page,
+		"page_size": page.PageSize,
+		"total":     total,
+	})
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// currentUserID returns the authenticated user's ID stored by AuthMiddleware.
+// It writes a 401 response and returns false when the ID is missing or malformed.
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(fmt.Sprint(value))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
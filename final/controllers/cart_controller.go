@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/inventory"
+	"final/models"
+	"final/promotions"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errInsufficientStock is returned when a cart line would exceed the variant's stock
+var errInsufficientStock = inventory.ErrInsufficientStock
+
+// getOrCreateCart returns the user's cart, creating it on first use
+func getOrCreateCart(db *gorm.DB, userID uuid.UUID) (models.ShoppingCart, error) {
+	var cart models.ShoppingCart
+	err := db.Where("user_id = ?", userID).First(&cart).Error
+	if err == nil {
+		return cart, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return cart, err
+	}
+
+	cart = models.ShoppingCart{UserID: userID}
+	if err := db.Create(&cart).Error; err != nil {
+		// Another request may have created the cart concurrently
+		if findErr := db.Where("user_id = ?", userID).First(&cart).Error; findErr != nil {
+			return cart, err
+		}
+	}
+	return cart, nil
+}
+
+// lockCart locks the cart row so that concurrent changes to its lines run one at a time
+func lockCart(tx *gorm.DB, cartID uuid.UUID) error {
+	var cart models.ShoppingCart
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("cart_id").
+		First(&cart, "cart_id = ?", cartID).Error
+}
+
+// loadCart loads the cart with its items, products and variants and computes the
+// subtotal. It only reads: a user who never added anything gets an empty cart.
+func loadCart(db *gorm.DB, userID uuid.UUID) (models.ShoppingCart, models.Money, error) {
+	subtotal := models.NewMoney(0)
+	var cart models.ShoppingCart
+	err := db.Preload("Items.Product").Preload("Items.Variant").First(&cart, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.ShoppingCart{UserID: userID, Items: []models.CartItem{}}, subtotal, nil
+	}
+	if err != nil {
+		return cart, subtotal, err
+	}
+
+	for _, item := range cart.Items {
+		subtotal = subtotal.Add(item.Variant.EffectivePrice(item.Product).Mul(item.Quantity))
+	}
+	return cart, subtotal, nil
+}
+
+// checkStock verifies that the requested quantity of a variant is available. reserved
+// is what the buyer's own reservation holds of it, which has already left Stock.
+func checkStock(product models.Product, variant models.ProductVariant, reserved, quantity int) error {
+	if available := variant.Stock + reserved; quantity > available {
+		return fmt.Errorf("%w: only %d of %q available", errInsufficientStock, available, variant.Label(product))
+	}
+	return nil
+}
+
+// reservedByUser returns how much of a variant the user's stock reservation holds
+func reservedByUser(db *gorm.DB, userID, variantID uuid.UUID) (int, error) {
+	var reserved int
+	err := db.Model(&models.StockReservation{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("user_id = ? AND variant_id = ?", userID, variantID).
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// cartLines converts cart items with their products and variants into lines for coupon rules
+func cartLines(items []models.CartItem) []promotions.Line {
+	lines := make([]promotions.Line, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, promotions.Line{
+			ProductID:  item.ProductID,
+			CategoryID: item.Product.CategoryID,
+			Quantity:   item.Quantity,
+			Price:      item.Variant.EffectivePrice(item.Product),
+		})
+	}
+	return lines
+}
+
+// addToCart adds a quantity of a variant to the cart, merging with an existing line
+// for the same variant, as long as the variant has enough stock for the merged line.
+// The cart is locked so that concurrent adds cannot create two lines for a variant.
+func addToCart(tx *gorm.DB, cart models.ShoppingCart, product models.Product, variant models.ProductVariant, quantity int) error {
+	if err := lockCart(tx, cart.CartID); err != nil {
+		return err
+	}
+	reserved, err := reservedByUser(tx, cart.UserID, variant.VariantID)
+	if err != nil {
+		return err
+	}
+
+	var item models.CartItem
+	err = tx.Where("cart_id = ? AND variant_id = ?", cart.CartID, variant.VariantID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := checkStock(product, variant, reserved, quantity); err != nil {
+			return err
+		}
+		item = models.CartItem{CartID: cart.CartID, ProductID: product.ProductID, VariantID: variant.VariantID, Quantity: quantity}
+		return tx.Create(&item).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	quantity += item.Quantity
+	if err := checkStock(product, variant, reserved, quantity); err != nil {
+		return err
+	}
+	return tx.Model(&item).Update("quantity", quantity).Error
+}
+
+// respondWithCart writes the user's current cart. When a coupon is applied the
+// response previews the discount, or explains why the coupon no longer applies.
+func respondWithCart(c *gin.Context, userID uuid.UUID, status int) {
+	cart, subtotal, err := loadCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	response := gin.H{"cart": cart, "subtotal": subtotal}
+	if cart.CouponCode != nil {
+		coupon, err := promotions.Find(config.DB, *cart.CouponCode)
+		var quote promotions.Quote
+		if err == nil {
+			quote, err = promotions.Apply(config.DB, coupon, userID, cartLines(cart.Items), time.Now())
+		}
+		switch {
+		case errors.Is(err, promotions.ErrInvalidCoupon):
+			response["coupon_error"] = err.Error()
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+			return
+		default:
+			response["discount"] = quote.Discount
+			response["total"] = quote.Total
+		}
+	}
+	c.JSON(status, response)
+}
+
+// Get the current user's cart
+func GetCart(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	respondWithCart(c, userID, http.StatusOK)
+}
+
+// Add a product variant to the cart, merging with an existing line for the same variant.
+// The variant may be left out for products that have only one.
+func AddCartItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ProductID uuid.UUID  `json:"product_id" binding:"required"`
+		VariantID *uuid.UUID `json:"variant_id"`
+		Quantity  int        `json:"quantity" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, "product_id = ?", input.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	variant, ok := resolveVariant(c, product, input.VariantID)
+	if !ok {
+		return
+	}
+
+	cart, err := getOrCreateCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return addToCart(tx, cart, product, variant, input.Quantity)
+	})
+	if errors.Is(err, errInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to cart"})
+		return
+	}
+
+	respondWithCart(c, userID, http.StatusCreated)
+}
+
+// Update the quantity of a cart item
+func UpdateCartItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Quantity int `json:"quantity" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, err := getOrCreateCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	// Checked and written under the cart lock, as adding an item is
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockCart(tx, cart.CartID); err != nil {
+			return err
+		}
+		var item models.CartItem
+		if err := tx.Preload("Product").Preload("Variant").
+			Where("cart_item_id = ? AND cart_id = ?", c.Param("id"), cart.CartID).
+			First(&item).Error; err != nil {
+			return err
+		}
+		reserved, err := reservedByUser(tx, userID, item.VariantID)
+		if err != nil {
+			return err
+		}
+		if err := checkStock(item.Product, item.Variant, reserved, input.Quantity); err != nil {
+			return err
+		}
+		return tx.Model(&item).Update("quantity", input.Quantity).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	case errors.Is(err, errInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart item"})
+		return
+	}
+
+	respondWithCart(c, userID, http.StatusOK)
+}
+
+// Remove an item from the cart
+func RemoveCartItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := getOrCreateCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	result := config.DB.Where("cart_item_id = ? AND cart_id = ?", c.Param("id"), cart.CartID).Delete(&models.CartItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove cart item"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
+	respondWithCart(c, userID, http.StatusOK)
+}
+
+// Reserve the cart's items for checkout. The stock is held for the reservation TTL;
+// reserving again replaces the user's previous reservation.
+func ReserveCart(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	cart, _, err := loadCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+	if len(cart.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+
+	// A cart has one line per variant, so each variant is reserved once
+	items := make([]inventory.Item, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		items = append(items, inventory.Item{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	var reservations []models.StockReservation
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		reservations, err = inventory.Reserve(tx, userID, items)
+		return err
+	})
+	switch {
+	case errors.Is(err, errInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusConflict, gin.H{"error": "A product in the cart no longer exists"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve stock"})
+		return
+	}
+
+	invalidateProductCache(c) // Stock changed
+	c.JSON(http.StatusCreated, gin.H{"reservations": reservations, "expires_at": reservations[0].ExpiresAt})
+}
+
+// Release the current user's stock reservation
+func ReleaseCartReservation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return inventory.Release(tx, userID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release reservation"})
+		return
+	}
+
+	invalidateProductCache(c) // Stock changed
+	c.JSON(http.StatusOK, gin.H{"message": "Reservation released"})
+}
+
+// Apply a coupon code to the cart and preview the discounted total. The code is
+// checked again when the order is placed.
+func ApplyCartCoupon(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Code string `json:"code" binding:"required,max=50"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, _, err := loadCart(config.DB, userID)
+	if err == nil && cart.CartID == uuid.Nil {
+		// The coupon is stored on the cart, so create it as adding an item would
+		cart, err = getOrCreateCart(config.DB, userID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	coupon, err := promotions.Find(config.DB, input.Code)
+	if err == nil {
+		_, err = promotions.Apply(config.DB, coupon, userID, cartLines(cart.Items), time.Now())
+	}
+	if errors.Is(err, promotions.ErrInvalidCoupon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+		return
+	}
+
+	if err := config.DB.Model(&cart).Update("coupon_code", coupon.Code).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+		return
+	}
+	respondWithCart(c, userID, http.StatusOK)
+}
+
+// Remove the coupon code from the cart
+func RemoveCartCoupon(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := getOrCreateCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+	if err := config.DB.Model(&cart).Update("coupon_code", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove coupon"})
+		return
+	}
+	respondWithCart(c, userID, http.StatusOK)
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errHasProducts is returned when deleting a category that still has products
+var errHasProducts = errors.New("category still has products")
+
+// categoryInput is the payload for creating or updating a category
+type categoryInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+// categoryExists reports whether a category with the given ID exists
+func categoryExists(db *gorm.DB, categoryID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Category{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count > 0, err
+}
+
+// categoryNameTaken reports whether another category already uses the name
+func categoryNameTaken(db *gorm.DB, name string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Category{}).Where("name = ? AND category_id <> ?", name, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// Create a new category
+func CreateCategory(c *gin.Context) {
+	var input categoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taken, err := categoryNameTaken(config.DB, input.Name, uuid.Nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category name"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists"})
+		return
+	}
+
+	category := models.Category{Name: input.Name, Description: input.Description}
+	if err := config.DB.Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+	recordAudit(c, "category.create", "category", category.CategoryID, nil, category)
+	c.JSON(http.StatusCreated, category)
+}
+
+// Get all categories
+func GetCategories(c *gin.Context) {
+	var categories []models.Category
+	if err := config.DB.Order("name").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+// Get a single category
+func GetCategory(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, "category_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// Update a category
+func UpdateCategory(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, "category_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var input categoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	taken, err := categoryNameTaken(config.DB, input.Name, category.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category name"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this name already exists"})
+		return
+	}
+
+	before := category
+	category.Name = input.Name
+	category.Description = input.Description
+	if err := config.DB.Save(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+	recordAudit(c, "category.update", "category", category.CategoryID, before, category)
+	c.JSON(http.StatusOK, category)
+}
+
+// Delete a category. Categories that still have products are only deleted when
+// ?reassign_to=<category_id> names a category to move those products to.
+func DeleteCategory(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, "category_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var reassignTo *uuid.UUID
+	if raw := c.Query("reassign_to"); raw != "" {
+		target, err := uuid.Parse(raw)
+		if err != nil || target == category.CategoryID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to must be the ID of another category"})
+			return
+		}
+		reassignTo = &target
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if reassignTo != nil {
+			exists, err := categoryExists(tx, *reassignTo)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return gorm.ErrRecordNotFound
+			}
+			if err := tx.Model(&models.Product{}).
+				Where("category_id = ?", category.CategoryID).
+				Update("category_id", *reassignTo).Error; err != nil {
+				return err
+			}
+		}
+
+		var count int64
+		if err := tx.Model(&models.Product{}).Where("category_id = ?", category.CategoryID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errHasProducts
+		}
+		return tx.Delete(&category).Error
+	})
+
+	switch {
+	case errors.Is(err, errHasProducts):
+		c.JSON(http.StatusConflict, gin.H{"error": "Category still has products; pass reassign_to to move them"})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reassignment category not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	if reassignTo != nil {
+		invalidateProductCache(c)
+	}
+	recordAudit(c, "category.delete", "category", category.CategoryID, category, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+}
+
+// Get the products of a category
+func GetCategoryProducts(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, "category_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Model(&models.Product{}).Where("category_id = ?", category.CategoryID).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	var products []models.Product
+	if err := query.Order("name").Offset(page.Offset()).Limit(page.PageSize).Preload("Variants", orderVariants).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      products,
+		"page":      page.Page,
+		"page_size": page.PageSize,
+		"total":     total,
+	})
+}
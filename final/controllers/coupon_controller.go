@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/models"
+	"final/promotions"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// couponInput is the payload for creating or updating a coupon
+type couponInput struct {
+	Code          string       `json:"code" binding:"required,max=50"`
+	Description   string       `json:"description"`
+	DiscountType  string       `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	PercentOff    int          `json:"percent_off"`
+	AmountOff     models.Money `json:"amount_off"`
+	MinOrderValue models.Money `json:"min_order_value"`
+	UsageLimit    *int         `json:"usage_limit" binding:"omitempty,min=1"`
+	PerUserLimit  *int         `json:"per_user_limit" binding:"omitempty,min=1"`
+	StartsAt      *time.Time   `json:"starts_at"`
+	EndsAt        *time.Time   `json:"ends_at"`
+	Active        *bool        `json:"active"`
+	ProductIDs    []uuid.UUID  `json:"product_ids"`
+	CategoryIDs   []uuid.UUID  `json:"category_ids"`
+}
+
+// validate checks the rules the binding tags cannot express
+func (input couponInput) validate() error {
+	switch input.DiscountType {
+	case models.DiscountPercentage:
+		if input.PercentOff < 1 || input.PercentOff > 100 {
+			return fmt.Errorf("percent_off must be between 1 and 100")
+		}
+	case models.DiscountFixed:
+		if !input.AmountOff.IsPositive() {
+			return fmt.Errorf("amount_off must be positive")
+		}
+	}
+	if input.MinOrderValue.IsNegative() {
+		return fmt.Errorf("min_order_value cannot be negative")
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.StartsAt.Before(*input.EndsAt) {
+		return fmt.Errorf("starts_at must be before ends_at")
+	}
+	return nil
+}
+
+// apply copies the input onto a coupon, resolving the product and category scope
+func (input couponInput) apply(db *gorm.DB, coupon *models.Coupon) error {
+	coupon.Code = promotions.NormalizeCode(input.Code)
+	coupon.Description = input.Description
+	coupon.DiscountType = input.DiscountType
+	coupon.PercentOff = 0
+	coupon.AmountOff = models.NewMoney(0)
+	if input.DiscountType == models.DiscountPercentage {
+		coupon.PercentOff = input.PercentOff
+	} else {
+		coupon.AmountOff = input.AmountOff
+	}
+	coupon.MinOrderValue = input.MinOrderValue
+	coupon.UsageLimit = input.UsageLimit
+	coupon.PerUserLimit = input.PerUserLimit
+	coupon.StartsAt = input.StartsAt
+	coupon.EndsAt = input.EndsAt
+	if input.Active != nil {
+		coupon.Active = *input.Active
+	}
+
+	coupon.Products = []models.Product{}
+	if len(input.ProductIDs) > 0 {
+		if err := db.Where("product_id IN ?", input.ProductIDs).Find(&coupon.Products).Error; err != nil {
+			return err
+		}
+		if len(coupon.Products) != len(uniqueIDs(input.ProductIDs)) {
+			return fmt.Errorf("%w: product", errScopeNotFound)
+		}
+	}
+	coupon.Categories = []models.Category{}
+	if len(input.CategoryIDs) > 0 {
+		if err := db.Where("category_id IN ?", input.CategoryIDs).Find(&coupon.Categories).Error; err != nil {
+			return err
+		}
+		if len(coupon.Categories) != len(uniqueIDs(input.CategoryIDs)) {
+			return fmt.Errorf("%w: category", errScopeNotFound)
+		}
+	}
+	return nil
+}
+
+// errScopeNotFound is returned when a coupon is scoped to a product or category that does not exist
+var errScopeNotFound = errors.New("coupon scope not found")
+
+// uniqueIDs drops repeated IDs
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// couponCodeTaken reports whether another coupon already uses the code
+func couponCodeTaken(db *gorm.DB, code string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Coupon{}).
+		Where("code = ? AND coupon_id <> ?", promotions.NormalizeCode(code), excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// replaceCouponScope rewrites the coupon's product and category join rows
+func replaceCouponScope(tx *gorm.DB, coupon models.Coupon) error {
+	if err := tx.Exec("DELETE FROM coupon_products WHERE coupon_id = ?", coupon.CouponID).Error; err != nil {
+		return err
+	}
+	for _, product := range coupon.Products {
+		if err := tx.Exec("INSERT INTO coupon_products (coupon_id, product_id) VALUES (?, ?)",
+			coupon.CouponID, product.ProductID).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("DELETE FROM coupon_categories WHERE coupon_id = ?", coupon.CouponID).Error; err != nil {
+		return err
+	}
+	for _, category := range coupon.Categories {
+		if err := tx.Exec("INSERT INTO coupon_categories (coupon_id, category_id) VALUES (?, ?)",
+			coupon.CouponID, category.CategoryID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveCoupon validates the input and writes the coupon with its scope
+func saveCoupon(c *gin.Context, coupon *models.Coupon, input couponInput) bool {
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	taken, err := couponCodeTaken(config.DB, input.Code, coupon.CouponID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check coupon code"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A coupon with this code already exists"})
+		return false
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := input.apply(tx, coupon); err != nil {
+			return err
+		}
+		if err := tx.Omit("Products", "Categories").Save(coupon).Error; err != nil {
+			return err
+		}
+		return replaceCouponScope(tx, *coupon)
+	})
+	if errors.Is(err, errScopeNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save coupon"})
+		return false
+	}
+	return true
+}
+
+// List all coupons, newest first
+func GetCoupons(c *gin.Context) {
+	var coupons []models.Coupon
+	if err := config.DB.Preload("Products").Preload("Categories").
+		Order("created_at DESC").
+		Find(&coupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coupons"})
+		return
+	}
+	c.JSON(http.StatusOK, coupons)
+}
+
+// Get a single coupon
+func GetCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := config.DB.Preload("Products").Preload("Categories").
+		First(&coupon, "coupon_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+	c.JSON(http.StatusOK, coupon)
+}
+
+// Create a coupon; it is active unless "active" is false
+func CreateCoupon(c *gin.Context) {
+	var input couponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coupon := models.Coupon{Active: true}
+	if !saveCoupon(c, &coupon, input) {
+		return
+	}
+	recordAudit(c, "coupon.create", "coupon", coupon.CouponID, nil, coupon)
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// Update a coupon, replacing its rules and scope
+func UpdateCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := config.DB.First(&coupon, "coupon_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	var input couponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := coupon
+	if !saveCoupon(c, &coupon, input) {
+		return
+	}
+	recordAudit(c, "coupon.update", "coupon", coupon.CouponID, before, coupon)
+	c.JSON(http.StatusOK, coupon)
+}
+
+// Delete a coupon no order has used; used coupons can be deactivated instead
+func DeleteCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := config.DB.First(&coupon, "coupon_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	// Orders keep a reference to their coupon even after a cancellation released it
+	var orders int64
+	if err := config.DB.Model(&models.Order{}).
+		Where("coupon_id = ?", coupon.CouponID).
+		Count(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check coupon usage"})
+		return
+	}
+	if orders > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon has been redeemed; deactivate it instead"})
+		return
+	}
+
+	if err := config.DB.Delete(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+	recordAudit(c, "coupon.delete", "coupon", coupon.CouponID, coupon, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted"})
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"final/config"
+	"final/models"
+	"final/storage"
+	"final/utils"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImageSize is the largest accepted upload in bytes
+	maxImageSize = 5 << 20
+	// maxImagePixels guards against decompression bombs
+	maxImagePixels = 40_000_000
+	// thumbnailSize is the longest side of generated thumbnails
+	thumbnailSize = 300
+)
+
+// imageExtensions lists the accepted image types by sniffed content type
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// encodeThumbnail renders the thumbnail as JPEG for JPEG sources and PNG otherwise
+func encodeThumbnail(img image.Image, contentType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", err
+}
+
+// deleteStoredImage removes an image's files, logging failures since the row is already gone
+func deleteStoredImage(ctx context.Context, img models.ProductImage) {
+	for _, key := range []string{img.StorageKey, img.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := storage.Default.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
+
+// Upload an image for a product
+func UploadProductImage(c *gin.Context) {
+	var product models.Product
+	if err := config.DB.First(&product, "product_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	// Leave room for the multipart headers around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageSize+1<<20)
+	header, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image must be at most 5 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Multipart field 'image' is required"})
+		return
+	}
+	if header.Size > maxImageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image must be at most 5 MB"})
+		return
+	}
+
+	position := -1
+	if raw := c.PostForm("position"); raw != "" {
+		position, err = strconv.Atoi(raw)
+		if err != nil || position < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "position must be a non-negative integer"})
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	var data bytes.Buffer
+	if _, err := data.ReadFrom(file); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+		return
+	}
+
+	// Trust the file's contents, not the client's Content-Type header
+	contentType := http.DetectContentType(data.Bytes())
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only JPEG, PNG and GIF images are supported"})
+		return
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data.Bytes()))
+	if err != nil || imageConfig.Width*imageConfig.Height > maxImagePixels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or oversized image"})
+		return
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image"})
+		return
+	}
+	thumbnail, thumbnailType, err := encodeThumbnail(utils.Thumbnail(decoded, thumbnailSize), contentType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate thumbnail"})
+		return
+	}
+
+	img := models.ProductImage{
+		ImageID:     uuid.New(),
+		ProductID:   product.ProductID,
+		ContentType: contentType,
+		Size:        int64(data.Len()),
+		Position:    position,
+	}
+	img.StorageKey = fmt.Sprintf("products/%s/%s%s", product.ProductID, img.ImageID, extension)
+	img.ThumbnailKey = fmt.Sprintf("products/%s/%s_thumb%s", product.ProductID, img.ImageID, imageExtensions[thumbnailType])
+	img.ImageURL = storage.Default.URL(img.StorageKey)
+	img.ThumbnailURL = storage.Default.URL(img.ThumbnailKey)
+
+	ctx := c.Request.Context()
+	if err := storage.Default.Put(ctx, img.StorageKey, bytes.NewReader(data.Bytes()), img.Size, contentType); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to store image"})
+		return
+	}
+	if err := storage.Default.Put(ctx, img.ThumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailType); err != nil {
+		deleteStoredImage(ctx, img)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to store thumbnail"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// New images go to the end unless a position was given
+		if img.Position < 0 {
+			var last struct{ Position *int }
+			if err := tx.Model(&models.ProductImage{}).
+				Select("MAX(position) AS position").
+				Where("product_id = ?", product.ProductID).
+				Scan(&last).Error; err != nil {
+				return err
+			}
+			img.Position = 0
+			if last.Position != nil {
+				img.Position = *last.Position + 1
+			}
+		}
+		return tx.Create(&img).Error
+	})
+	if err != nil {
+		deleteStoredImage(ctx, img)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return
+	}
+
+	invalidateProductCache(c, productCacheTag(product.ProductID))
+	recordAudit(c, "product_image.create", "product_image", img.ImageID, nil, img)
+	c.JSON(http.StatusCreated, img)
+}
+
+// Delete a product image and its stored files
+func DeleteProductImage(c *gin.Context) {
+	var img models.ProductImage
+	if err := config.DB.Where("image_id = ? AND product_id = ?", c.Param("image_id"), c.Param("id")).
+		First(&img).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	if err := config.DB.Delete(&img).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+	deleteStoredImage(c.Request.Context(), img)
+
+	invalidateProductCache(c, productCacheTag(img.ProductID))
+	recordAudit(c, "product_image.delete", "product_image", img.ImageID, img, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/inventory"
+	"final/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Adjust a variant's stock by a signed quantity. The variant may be left out for
+// products that have only one. Restocks must add stock and damage must remove it;
+// corrections may go either way.
+func AdjustStock(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		VariantID *uuid.UUID `json:"variant_id"`
+		Quantity  int        `json:"quantity" binding:"required"`
+		Reason    string     `json:"reason" binding:"required,oneof=restock damage correction"`
+		Note      string     `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Reason == models.StockReasonRestock && input.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A restock must add stock"})
+		return
+	}
+	if input.Reason == models.StockReasonDamage && input.Quantity > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Damage must remove stock"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, "product_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	variant, ok := resolveVariant(c, product, input.VariantID)
+	if !ok {
+		return
+	}
+
+	movement := models.StockMovement{
+		ProductID: product.ProductID,
+		VariantID: variant.VariantID,
+		Quantity:  input.Quantity,
+		Reason:    input.Reason,
+		Note:      input.Note,
+		ActorID:   &actorID,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return inventory.Adjust(tx, &movement)
+	})
+	switch {
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust stock"})
+		return
+	}
+
+	invalidateProductCache(c)
+	recordAudit(c, "inventory.adjust", "product_variant", variant.VariantID,
+		gin.H{"stock": movement.StockAfter - movement.Quantity},
+		gin.H{"stock": movement.StockAfter, "reason": movement.Reason, "note": movement.Note})
+	c.JSON(http.StatusCreated, movement)
+}
+
+// List the stock movements of a product, newest first, optionally filtered by
+// variant and reason
+func GetStockMovements(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, "product_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	query := config.DB.Model(&models.StockMovement{}).Where("product_id = ?", product.ProductID)
+	if variantID := c.Query("variant_id"); variantID != "" {
+		parsed, err := uuid.Parse(variantID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "variant_id must be a UUID"})
+			return
+		}
+		query = query.Where("variant_id = ?", parsed)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock movements"})
+		return
+	}
+
+	var movements []models.StockMovement
+	if err := query.Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.PageSize).
+		Find(&movements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock movements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": product.ProductID,
+		"stock":      product.Stock,
+		"data":       movements,
+		"page":       page.Page,
+		"page_size":  page.PageSize,
+		"total":      total,
+	})
+}
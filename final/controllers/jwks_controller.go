@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"final/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Publish the public keys that verify access tokens
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/inventory"
+	"final/models"
+	"final/pricing"
+	"final/promotions"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errEmptyCart is returned when checking out a cart without items
+var errEmptyCart = errors.New("cart is empty")
+
+// Checkout turns the user's cart into an order in a single transaction
+func Checkout(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	// Addresses default to the user's default shipping and billing addresses, the
+	// coupon to the one applied to the cart and shipping to the cheapest method
+	var input struct {
+		ShippingAddressID *uuid.UUID `json:"shipping_address_id"`
+		BillingAddressID  *uuid.UUID `json:"billing_address_id"`
+		CouponCode        *string    `json:"coupon_code" binding:"omitempty,max=50"`
+		ShippingMethod    string     `json:"shipping_method" binding:"max=50"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the cart so two checkouts of the same cart are serialized
+		var cart models.ShoppingCart
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).First(&cart).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errEmptyCart
+			}
+			return err
+		}
+
+		var items []models.CartItem
+		if err := tx.Where("cart_id = ?", cart.CartID).Find(&items).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return errEmptyCart
+		}
+
+		// Collect the quantity of each variant in the cart
+		quantities := make(map[uuid.UUID]int)
+		productIDs := make([]uuid.UUID, 0, len(items))
+		variantIDs := make([]uuid.UUID, 0, len(items))
+		for _, item := range items {
+			if _, ok := quantities[item.VariantID]; !ok {
+				productIDs = append(productIDs, item.ProductID)
+				variantIDs = append(variantIDs, item.VariantID)
+			}
+			quantities[item.VariantID] += item.Quantity
+		}
+
+		// Lock the user's reservations and then every product they or the cart hold,
+		// in one pass ordered by product. Releasing the reservations and selling the
+		// stock below only lock rows of products held by now, so two checkouts
+		// cannot lock the same products in opposite orders.
+		reservations, err := inventory.LockReservations(tx, userID)
+		if err != nil {
+			return err
+		}
+		lockIDs := append([]uuid.UUID{}, productIDs...)
+		for _, reservation := range reservations {
+			lockIDs = append(lockIDs, reservation.ProductID)
+		}
+		products, err := inventory.LockProducts(tx, lockIDs)
+		if err != nil {
+			return err
+		}
+
+		// Stock the user reserved for this checkout goes back before it is sold
+		if err := inventory.ReleaseReservations(tx, reservations); err != nil {
+			return err
+		}
+
+		// Variants are locked after their products, as inventory.Adjust does
+		var variants []models.ProductVariant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("variant_id IN ?", variantIDs).
+			Order("product_id, variant_id").
+			Find(&variants).Error; err != nil {
+			return err
+		}
+		if len(variants) != len(variantIDs) {
+			return gorm.ErrRecordNotFound
+		}
+		productsByID := make(map[uuid.UUID]models.Product, len(products))
+		for _, product := range products {
+			productsByID[product.ProductID] = product
+		}
+
+		shipping, err := findCheckoutAddress(tx, userID, input.ShippingAddressID, "is_default_shipping")
+		if err != nil {
+			return err
+		}
+		if shipping == nil {
+			return errAddressRequired
+		}
+		billing, err := findCheckoutAddress(tx, userID, input.BillingAddressID, "is_default_billing")
+		if err != nil {
+			return err
+		}
+		if billing == nil {
+			billing = shipping
+		}
+
+		order = models.Order{
+			UserID:          userID,
+			OrderDate:       time.Now(),
+			Status:          models.OrderStatusPending,
+			DiscountAmount:  models.NewMoney(0),
+			ShippingAddress: snapshotAddress(*shipping),
+			BillingAddress:  snapshotAddress(*billing),
+		}
+		lines := make([]promotions.Line, 0, len(variants))
+		priced := make([]pricing.Line, 0, len(variants))
+		for _, variant := range variants {
+			product := productsByID[variant.ProductID]
+			quantity := quantities[variant.VariantID]
+			// The user's reservation was released above, so its units are back in Stock
+			if err := checkStock(product, variant, 0, quantity); err != nil {
+				return err
+			}
+
+			price := variant.EffectivePrice(product)
+			variantID := variant.VariantID
+			order.Items = append(order.Items, models.OrderItem{
+				ProductID:      product.ProductID,
+				VariantID:      &variantID,
+				SKU:            variant.SKU,
+				Options:        variant.Options,
+				Quantity:       quantity,
+				Price:          price,
+				DiscountAmount: models.NewMoney(0),
+			})
+			lines = append(lines, promotions.Line{
+				ProductID:  product.ProductID,
+				CategoryID: product.CategoryID,
+				Quantity:   quantity,
+				Price:      price,
+			})
+			priced = append(priced, pricingLine(product, variant, quantity))
+		}
+
+		// The coupon is locked and validated again, so its limits hold under concurrent checkouts
+		code := input.CouponCode
+		if code == nil {
+			code = cart.CouponCode
+		}
+		var coupon models.Coupon
+		if code != nil && *code != "" {
+			var err error
+			if coupon, err = promotions.Lock(tx, *code); err != nil {
+				return err
+			}
+			applied, err := promotions.Apply(tx, coupon, userID, lines, order.OrderDate)
+			if err != nil {
+				return err
+			}
+			order.DiscountAmount = applied.Discount
+			// Lines were built alongside the items, so they share an index
+			for i := range order.Items {
+				order.Items[i].DiscountAmount = applied.LineDiscounts[i]
+			}
+			order.CouponID = &coupon.CouponID
+			order.CouponCode = coupon.Code
+		}
+
+		// Shipping and tax are calculated once here and stored with the order
+		quote, err := pricing.Calculate(tx, pricing.Request{
+			Lines:          priced,
+			Discount:       order.DiscountAmount,
+			Address:        order.ShippingAddress,
+			ShippingMethod: input.ShippingMethod,
+		})
+		if err != nil {
+			return err
+		}
+		applyQuote(&order, quote)
+
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		// The ledger entries reference the order, so stock is taken once it exists
+		for _, item := range order.Items {
+			if err := inventory.Adjust(tx, &models.StockMovement{
+				ProductID: item.ProductID,
+				VariantID: *item.VariantID,
+				Quantity:  -item.Quantity,
+				Reason:    models.StockReasonSale,
+				OrderID:   &order.OrderID,
+				ActorID:   &userID,
+			}); err != nil {
+				return err
+			}
+		}
+		if order.CouponID != nil {
+			if err := promotions.Redeem(tx, coupon, userID, order.OrderID, order.DiscountAmount); err != nil {
+				return err
+			}
+		}
+		if err := recordStatusChange(tx, order.OrderID, "", order.Status, userID, "Order placed"); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&cart).Update("coupon_code", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("cart_id = ?", cart.CartID).Delete(&models.CartItem{}).Error
+	})
+
+	switch {
+	case errors.Is(err, errEmptyCart):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	case errors.Is(err, errAddressRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A shipping address is required; add one to your address book"})
+		return
+	case errors.Is(err, errAddressNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Address not found"})
+		return
+	case errors.Is(err, promotions.ErrInvalidCoupon), errors.Is(err, pricing.ErrShippingUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusConflict, gin.H{"error": "A product in the cart no longer exists"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place order"})
+		return
+	}
+
+	invalidateProductCache(c) // Stock changed
+	recordAudit(c, "order.checkout", "order", order.OrderID, nil, order)
+	c.JSON(http.StatusCreated, order)
+}
+
+// Get the current user's orders
+func GetOrders(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var orders []models.Order
+	if err := config.DB.Preload("Items").
+		Where("user_id = ?", userID).
+		Order("order_date DESC").
+		Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// Get a single order of the current user
+func GetOrder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("Items.Product").
+		Where("order_id = ? AND user_id = ?", c.Param("id"), userID).
+		First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
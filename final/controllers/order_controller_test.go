@@ -0,0 +1,114 @@
+package controllers_test
+
+import (
+	"final/config"
+	"final/controllers"
+	"final/models"
+	"final/pricing"
+	"final/testdb"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// removeCheckoutRows deletes what a committed checkout test created
+func removeCheckoutRows(db *gorm.DB, product models.Product, userIDs []uuid.UUID) {
+	orders := db.Model(&models.Order{}).Select("order_id").Where("user_id IN ?", userIDs)
+	carts := db.Model(&models.ShoppingCart{}).Select("cart_id").Where("user_id IN ?", userIDs)
+	db.Where("order_id IN (?)", orders).Delete(&models.StockMovement{})
+	db.Where("order_id IN (?)", orders).Delete(&models.OrderStatusHistory{})
+	db.Where("order_id IN (?)", orders).Delete(&models.OrderItem{})
+	db.Where("user_id IN ?", userIDs).Delete(&models.Order{})
+	db.Where("cart_id IN (?)", carts).Delete(&models.CartItem{})
+	db.Where("user_id IN ?", userIDs).Delete(&models.ShoppingCart{})
+	db.Where("user_id IN ?", userIDs).Delete(&models.UserAddress{})
+	db.Where("data->>'product_id' = ?", product.ProductID.String()).Delete(&models.Notification{})
+	db.Where("product_id = ?", product.ProductID).Delete(&models.Product{})
+	db.Where("category_id = ?", product.CategoryID).Delete(&models.Category{})
+	db.Where("user_id IN ?", userIDs).Delete(&models.User{})
+}
+
+func TestConcurrentCheckoutsSellTheLastUnitOnce(t *testing.T) {
+	// Checkouts run in their own transactions, so the rows they see must be committed
+	db := testdb.Shared(t)
+	previousDB, previousTax, previousShipping := config.DB, pricing.Tax, pricing.Shipping
+	config.DB, pricing.Tax, pricing.Shipping = db, pricing.NoTax{}, pricing.FreeShipping{}
+	t.Cleanup(func() { config.DB, pricing.Tax, pricing.Shipping = previousDB, previousTax, previousShipping })
+
+	product, variant := testdb.Product(t, db, 1)
+	const buyers = 5
+	userIDs := make([]uuid.UUID, buyers)
+	for i := range userIDs {
+		userIDs[i] = testdb.User(t, db, models.RoleUser).UserID
+	}
+	t.Cleanup(func() { removeCheckoutRows(db, product, userIDs) })
+
+	for _, userID := range userIDs {
+		address := models.UserAddress{UserID: userID, Street: "1 Main St", City: "Springfield", ZipCode: "12345", Country: "US", IsDefaultShipping: true}
+		cart := models.ShoppingCart{UserID: userID}
+		if err := db.Create(&address).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&cart).Error; err != nil {
+			t.Fatal(err)
+		}
+		item := models.CartItem{CartID: cart.CartID, ProductID: product.ProductID, VariantID: variant.VariantID, Quantity: 1}
+		if err := db.Create(&item).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/checkout", func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+	}, controllers.Checkout)
+
+	statuses := make([]int, buyers)
+	var wg sync.WaitGroup
+	for i, userID := range userIDs {
+		wg.Add(1)
+		go func(i int, userID uuid.UUID) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/checkout", nil)
+			req.Header.Set("X-User-ID", userID.String())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			statuses[i] = w.Code
+		}(i, userID)
+	}
+	wg.Wait()
+
+	placed := 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusCreated:
+			placed++
+		case http.StatusConflict:
+		default:
+			t.Errorf("checkout status = %d, want %d or %d", status, http.StatusCreated, http.StatusConflict)
+		}
+	}
+	if placed != 1 {
+		t.Errorf("successful checkouts = %d, want 1", placed)
+	}
+
+	var orders int64
+	if err := db.Model(&models.Order{}).Where("user_id IN ?", userIDs).Count(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orders != 1 {
+		t.Errorf("orders = %d, want 1", orders)
+	}
+	if err := db.First(&variant, "variant_id = ?", variant.VariantID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if variant.Stock != 0 {
+		t.Errorf("variant stock = %d, want 0", variant.Stock)
+	}
+}
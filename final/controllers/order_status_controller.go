@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/inventory"
+	"final/middlewares"
+	"final/models"
+	"final/promotions"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// orderTransitions lists the statuses an order may move to from each status
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:   {models.OrderStatusPaid, models.OrderStatusCancelled},
+	models.OrderStatusPaid:      {models.OrderStatusShipped, models.OrderStatusCancelled, models.OrderStatusRefunded},
+	models.OrderStatusShipped:   {models.OrderStatusDelivered},
+	models.OrderStatusDelivered: {models.OrderStatusRefunded},
+	models.OrderStatusCancelled: {},
+	models.OrderStatusRefunded:  {},
+}
+
+// errInvalidTransition is returned when a status change is not in orderTransitions
+var errInvalidTransition = errors.New("invalid status transition")
+
+// canTransition reports whether an order may move from one status to another
+func canTransition(from, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// recordStatusChange appends an entry to the order's status history
+func recordStatusChange(tx *gorm.DB, orderID uuid.UUID, from, to string, actorID uuid.UUID, note string) error {
+	return tx.Create(&models.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedBy:  actorID,
+		Note:       note,
+		ChangedAt:  time.Now(),
+	}).Error
+}
+
+// restockOrder returns the order's items to their variants' stock. Items whose
+// variant has since been deleted have nowhere to go back to and are skipped.
+func restockOrder(tx *gorm.DB, orderID, actorID uuid.UUID) error {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ? AND variant_id IS NOT NULL", orderID).
+		Order("product_id, variant_id").
+		Find(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := inventory.Adjust(tx, &models.StockMovement{
+			ProductID: item.ProductID,
+			VariantID: *item.VariantID,
+			Quantity:  item.Quantity,
+			Reason:    models.StockReasonCancellation,
+			OrderID:   &orderID,
+			ActorID:   &actorID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transitionOrder moves a locked order to a new status. Cancelled orders are restocked
+// and give back their coupon use; orders with a charge in progress cannot be cancelled.
+func transitionOrder(tx *gorm.DB, order *models.Order, to string, actorID uuid.UUID, note string) error {
+	if !canTransition(order.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", errInvalidTransition, order.Status, to)
+	}
+
+	if to == models.OrderStatusCancelled {
+		// A charge waiting for the provider may still go through
+		pending, err := findPendingCharge(tx, order.OrderID)
+		if err != nil {
+			return err
+		}
+		if pending != nil {
+			return fmt.Errorf("%w: a payment for the order is in progress", errInvalidTransition)
+		}
+		if err := restockOrder(tx, order.OrderID, actorID); err != nil {
+			return err
+		}
+		if err := promotions.Release(tx, order.OrderID); err != nil {
+			return err
+		}
+	}
+
+	from := order.Status
+	if err := tx.Model(order).Update("status", to).Error; err != nil {
+		return err
+	}
+	return recordStatusChange(tx, order.OrderID, from, to, actorID, note)
+}
+
+// lockOrder loads an order with a row lock for the rest of the transaction
+func lockOrder(tx *gorm.DB, order *models.Order, query string, args ...interface{}) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).First(order).Error
+}
+
+// respondTransitionError maps transition errors to HTTP responses
+func respondTransitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+	}
+}
+
+// Move an order to a new status (admin only). Cancelling a paid order refunds its
+// payments in full. Orders only become paid or refunded through the payment and
+// refund endpoints, which record the money that moved.
+func UpdateOrderStatus(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Status string `json:"status" binding:"required,oneof=shipped delivered cancelled"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var order models.Order
+	var refunds []models.Payment
+	from := ""
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, "order_id = ?", c.Param("id")); err != nil {
+			return err
+		}
+		from = order.Status
+
+		// Cancelling a paid order gives the customer their money back
+		if input.Status == models.OrderStatusCancelled && order.Status == models.OrderStatusPaid {
+			var err error
+			if refunds, err = refundOrder(tx, order); err != nil {
+				return err
+			}
+		}
+		return transitionOrder(tx, &order, input.Status, actorID, input.Note)
+	})
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+
+	// The refunds reach the provider only now that the cancellation has committed
+	if len(refunds) > 0 {
+		refunds, err = settleRefunds(c.Request.Context(), config.DB, order.OrderID)
+	}
+
+	if order.Status == models.OrderStatusCancelled {
+		invalidateProductCache(c) // Stock was returned
+	}
+	after := gin.H{"status": order.Status, "note": input.Note}
+	if len(refunds) > 0 {
+		after["refunds"] = refunds
+	}
+	recordAudit(c, "order.status_change", "order", order.OrderID, gin.H{"status": from}, after)
+	if err != nil {
+		respondRefundError(c, err, gin.H{"order": order, "refunds": refunds})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// Cancel one of the current user's orders while it is still pending
+func CancelOrder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockOrder(tx, &order, "order_id = ? AND user_id = ?", c.Param("id"), userID); err != nil {
+			return err
+		}
+		if order.Status != models.OrderStatusPending {
+			return fmt.Errorf("%w: only pending orders can be cancelled", errInvalidTransition)
+		}
+		return transitionOrder(tx, &order, models.OrderStatusCancelled, userID, "Cancelled by customer")
+	})
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+
+	invalidateProductCache(c) // Stock was returned
+	recordAudit(c, "order.cancel", "order", order.OrderID, gin.H{"status": models.OrderStatusPending}, gin.H{"status": order.Status})
+	c.JSON(http.StatusOK, order)
+}
+
+// Get the status history of an order (owner or order managers)
+func GetOrderHistory(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("order_id = ?", c.Param("id"))
+	if !middlewares.HasPermission(c, models.PermissionOrderManage) {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var order models.Order
+	if err := query.Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("changed_at")
+	}).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order.History)
+}
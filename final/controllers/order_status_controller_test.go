@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"final/models"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusPaid, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusPending, models.OrderStatusShipped, false},
+		{models.OrderStatusPending, models.OrderStatusRefunded, false},
+		{models.OrderStatusPaid, models.OrderStatusShipped, true},
+		{models.OrderStatusPaid, models.OrderStatusCancelled, true},
+		{models.OrderStatusPaid, models.OrderStatusRefunded, true},
+		{models.OrderStatusPaid, models.OrderStatusDelivered, false},
+		{models.OrderStatusShipped, models.OrderStatusDelivered, true},
+		{models.OrderStatusShipped, models.OrderStatusCancelled, false},
+		{models.OrderStatusDelivered, models.OrderStatusRefunded, true},
+		{models.OrderStatusDelivered, models.OrderStatusShipped, false},
+		{models.OrderStatusCancelled, models.OrderStatusPending, false},
+		{models.OrderStatusRefunded, models.OrderStatusPaid, false},
+		{models.OrderStatusPaid, models.OrderStatusPaid, false},
+		{"unknown", models.OrderStatusPaid, false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFinalStatusesHaveNoTransitions(t *testing.T) {
+	for _, status := range []string{models.OrderStatusCancelled, models.OrderStatusRefunded} {
+		if next := orderTransitions[status]; len(next) != 0 {
+			t.Errorf("%s order can move to %v, want none", status, next)
+		}
+	}
+}
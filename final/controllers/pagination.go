@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pagination holds the page requested through the page and page_size query parameters
+type pagination struct {
+	Page     int
+	PageSize int
+}
+
+// Offset returns the number of rows to skip for the page
+func (p pagination) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// parsePagination reads page and page_size, rejecting values that are not positive integers
+func parsePagination(c *gin.Context) (pagination, error) {
+	p := pagination{Page: 1, PageSize: defaultPageSize}
+
+	if raw := c.Query("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return p, fmt.Errorf("page must be a positive integer")
+		}
+		p.Page = page
+	}
+
+	if raw := c.Query("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > maxPageSize {
+			return p, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+		p.PageSize = size
+	}
+
+	return p, nil
+}
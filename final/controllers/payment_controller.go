@@ -0,0 +1,500 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"final/config"
+	"final/middlewares"
+	"final/models"
+	"final/payments"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// errAmountMismatch is returned when a payment does not cover the order total
+	errAmountMismatch = errors.New("payment amount does not match order total")
+	// errIdempotencyConflict is returned when a refund key is reused for a different payment
+	errIdempotencyConflict = errors.New("idempotency key already used for another payment")
+	// errRefundTooLarge is returned when refunds would exceed the original charge
+	errRefundTooLarge = errors.New("refund exceeds the remaining refundable amount")
+)
+
+// idempotencyKey reads the Idempotency-Key header, returning nil when absent
+func idempotencyKey(c *gin.Context) *string {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		return nil
+	}
+	return &key
+}
+
+// paymentOperation is what an idempotency key was used for. Keys are scoped to an
+// order and an operation, so paying and refunding an order never replay each other.
+type paymentOperation string
+
+const (
+	operationCharge paymentOperation = "charge"
+	operationRefund paymentOperation = "refund"
+)
+
+// findPaymentByKey returns the charge or refund of an order previously stored
+// under the idempotency key
+func findPaymentByKey(tx *gorm.DB, orderID uuid.UUID, operation paymentOperation, key *string) (*models.Payment, error) {
+	if key == nil {
+		return nil, nil
+	}
+	query := tx.Where("order_id = ? AND idempotency_key = ?", orderID, *key)
+	if operation == operationRefund {
+		query = query.Where("refund_of_id IS NOT NULL")
+	} else {
+		query = query.Where("refund_of_id IS NULL")
+	}
+	var payment models.Payment
+	err := query.First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// providerKey scopes an idempotency key for the payment provider, which shares one
+// key space between all orders and operations
+func providerKey(orderID uuid.UUID, operation paymentOperation, key *string) string {
+	if key == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s:%s", orderID, operation, *key)
+}
+
+// refundable returns what is left to refund of a charge loaded with its refunds.
+// Pending refunds count, since they are on their way to the provider.
+func refundable(original models.Payment) models.Money {
+	// Refunds are stored as negative amounts
+	remaining := original.Amount
+	for _, previous := range original.Refunds {
+		if previous.Status != models.PaymentStatusFailed {
+			remaining = remaining.Add(previous.Amount)
+		}
+	}
+	return remaining
+}
+
+// recordRefund stores a pending refund of part of a charge. It reaches the provider
+// through settleRefunds once the transaction has committed, so a change that rolls
+// back never refunds the customer.
+func recordRefund(tx *gorm.DB, original models.Payment, amount models.Money, key *string) (models.Payment, error) {
+	refund := models.Payment{
+		OrderID:        original.OrderID,
+		Amount:         amount.Neg(),
+		PaymentDate:    time.Now(),
+		PaymentMethod:  original.PaymentMethod,
+		Provider:       payments.Provider.Name(),
+		IdempotencyKey: key,
+		RefundOfID:     &original.PaymentID,
+		Status:         models.PaymentStatusPending,
+	}
+	return refund, tx.Create(&refund).Error
+}
+
+// settleRefund sends a pending refund to the provider. The provider key is derived
+// from the refund itself, so sending it again after a lost response cannot refund
+// twice. A declined refund is marked failed; one the provider could not be reached
+// for stays pending.
+func settleRefund(ctx context.Context, db *gorm.DB, refund *models.Payment) error {
+	var original models.Payment
+	if err := db.First(&original, "payment_id = ?", *refund.RefundOfID).Error; err != nil {
+		return err
+	}
+
+	refundID := refund.PaymentID.String()
+	result, err := payments.Provider.Refund(ctx, payments.RefundRequest{
+		Reference:      original.Reference,
+		Amount:         refund.Amount.Neg(),
+		IdempotencyKey: providerKey(refund.OrderID, operationRefund, &refundID),
+	})
+	if errors.Is(err, payments.ErrDeclined) {
+		refund.Status = models.PaymentStatusFailed
+		if updateErr := db.Model(refund).Update("status", refund.Status).Error; updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	refund.Status, refund.Reference = models.PaymentStatusCompleted, result.Reference
+	return db.Model(refund).Updates(map[string]interface{}{"status": refund.Status, "reference": refund.Reference}).Error
+}
+
+// settleRefunds sends every pending refund of an order to the provider, including
+// ones left pending by earlier requests, and returns them with their outcome.
+// It stops at the first refund that does not complete.
+func settleRefunds(ctx context.Context, db *gorm.DB, orderID uuid.UUID) ([]models.Payment, error) {
+	var pending []models.Payment
+	if err := db.Where("order_id = ? AND refund_of_id IS NOT NULL AND status = ?", orderID, models.PaymentStatusPending).
+		Order("payment_date").
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		if err := settleRefund(ctx, db, &pending[i]); err != nil {
+			return pending, err
+		}
+	}
+	return pending, nil
+}
+
+// refundOrder records a pending refund of what remains of every charge of a locked
+// order, for cancelling an order that was paid
+func refundOrder(tx *gorm.DB, order models.Order) ([]models.Payment, error) {
+	var charges []models.Payment
+	if err := tx.Preload("Refunds").
+		Where("order_id = ? AND refund_of_id IS NULL AND status = ?", order.OrderID, models.PaymentStatusCompleted).
+		Order("payment_date").
+		Find(&charges).Error; err != nil {
+		return nil, err
+	}
+
+	var refunds []models.Payment
+	for _, charge := range charges {
+		remaining := refundable(charge)
+		if !remaining.IsPositive() {
+			continue
+		}
+		refund, err := recordRefund(tx, charge, remaining, nil)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, nil
+}
+
+// respondRefundError reports a refund that was recorded but did not complete
+// after the rest of the request had committed
+func respondRefundError(c *gin.Context, err error, body gin.H) {
+	switch {
+	case errors.Is(err, payments.ErrDeclined):
+		body["error"] = "The refund was declined: " + err.Error()
+		c.JSON(http.StatusPaymentRequired, body)
+	default:
+		body["error"] = "The refund is recorded but could not be sent to the payment provider; refunding the order again retries it"
+		c.JSON(http.StatusAccepted, body)
+	}
+}
+
+// respondPaymentError maps payment errors to HTTP responses
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order or payment not found"})
+	case errors.Is(err, errAmountMismatch), errors.Is(err, errRefundTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errIdempotencyConflict), errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrDeclined):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment"})
+	}
+}
+
+// findPendingCharge returns the charge of an order that is waiting for the provider
+func findPendingCharge(tx *gorm.DB, orderID uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	err := tx.Where("order_id = ? AND refund_of_id IS NULL AND status = ?", orderID, models.PaymentStatusPending).
+		First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// settleCharge sends a pending charge to the provider and, once it is accepted,
+// completes it and marks the order paid in a second transaction. The provider key
+// is derived from the charge itself, so retrying after a lost response or a failed
+// commit cannot charge twice. A declined charge is marked failed; one the provider
+// could not be reached for stays pending.
+func settleCharge(ctx context.Context, db *gorm.DB, payment *models.Payment, actorID uuid.UUID) error {
+	paymentID := payment.PaymentID.String()
+	result, err := payments.Provider.Charge(ctx, payments.ChargeRequest{
+		OrderID:        payment.OrderID,
+		Amount:         payment.Amount,
+		Method:         payment.PaymentMethod,
+		IdempotencyKey: providerKey(payment.OrderID, operationCharge, &paymentID),
+	})
+	if errors.Is(err, payments.ErrDeclined) {
+		payment.Status = models.PaymentStatusFailed
+		if updateErr := db.Model(payment).Where("status = ?", models.PaymentStatusPending).
+			Update("status", payment.Status).Error; updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, &order, "order_id = ?", payment.OrderID); err != nil {
+			return err
+		}
+		completed := tx.Model(&models.Payment{}).
+			Where("payment_id = ? AND status = ?", payment.PaymentID, models.PaymentStatusPending).
+			Updates(map[string]interface{}{"status": models.PaymentStatusCompleted, "reference": result.Reference})
+		if completed.Error != nil {
+			return completed.Error
+		}
+		payment.Status, payment.Reference = models.PaymentStatusCompleted, result.Reference
+		if completed.RowsAffected == 0 {
+			// A concurrent retry settled the charge first
+			return nil
+		}
+		return transitionOrder(tx, &order, models.OrderStatusPaid, actorID, "Payment "+result.Reference)
+	})
+}
+
+// Pay for one of the current user's pending orders. The charge is stored as pending
+// before the provider is called, so a charge never goes unrecorded; orders that
+// cost nothing are paid without the provider.
+func PayOrder(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		PaymentMethod string        `json:"payment_method" binding:"required,max=50"`
+		Amount        *models.Money `json:"amount"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key := idempotencyKey(c)
+
+	var payment models.Payment
+	replayed := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, &order, "order_id = ? AND user_id = ?", c.Param("id"), userID); err != nil {
+			return err
+		}
+
+		// A retried request continues the charge created by the first attempt, and
+		// a charge still waiting for the provider is continued rather than repeated
+		existing, err := findPaymentByKey(tx, order.OrderID, operationCharge, key)
+		if err == nil && existing == nil {
+			existing, err = findPendingCharge(tx, order.OrderID)
+		}
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			payment = *existing
+			replayed = true
+			if payment.Status == models.PaymentStatusFailed {
+				return fmt.Errorf("%w: this payment attempt was declined", payments.ErrDeclined)
+			}
+			return nil
+		}
+
+		if !canTransition(order.Status, models.OrderStatusPaid) {
+			return fmt.Errorf("%w: order is %s", errInvalidTransition, order.Status)
+		}
+		if input.Amount != nil && input.Amount.Cmp(order.TotalAmount) != 0 {
+			return fmt.Errorf("%w: expected %s", errAmountMismatch, order.TotalAmount)
+		}
+
+		payment = models.Payment{
+			OrderID:        order.OrderID,
+			Amount:         order.TotalAmount,
+			PaymentDate:    time.Now(),
+			PaymentMethod:  input.PaymentMethod,
+			Provider:       payments.Provider.Name(),
+			IdempotencyKey: key,
+			Status:         models.PaymentStatusPending,
+		}
+		// Nothing to collect, as when a coupon covers the whole order
+		if !order.TotalAmount.IsPositive() {
+			payment.Provider = ""
+			payment.Status = models.PaymentStatusCompleted
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			return transitionOrder(tx, &order, models.OrderStatusPaid, userID, "Nothing to pay")
+		}
+		return tx.Create(&payment).Error
+	})
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	wasPending := payment.Status == models.PaymentStatusPending
+	if wasPending {
+		err = settleCharge(c.Request.Context(), config.DB, &payment, userID)
+	}
+	if !replayed || (wasPending && payment.Status != models.PaymentStatusPending) {
+		recordAudit(c, "order.pay", "order", payment.OrderID, nil, payment)
+	}
+	if err != nil {
+		if errors.Is(err, payments.ErrDeclined) || errors.Is(err, errInvalidTransition) {
+			respondPaymentError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"error":   "The payment is recorded but could not be confirmed with the payment provider; paying the order again retries it",
+			"payment": payment,
+		})
+		return
+	}
+
+	status := http.StatusCreated
+	if replayed {
+		status = http.StatusOK
+	}
+	c.JSON(status, payment)
+}
+
+// Refund part or all of a payment (admin only)
+func RefundPayment(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		PaymentID uuid.UUID     `json:"payment_id" binding:"required"`
+		Amount    *models.Money `json:"amount"`
+		Reason    string        `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Amount != nil && !input.Amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		return
+	}
+	key := idempotencyKey(c)
+
+	var refund models.Payment
+	replayed := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := lockOrder(tx, &order, "order_id = ?", c.Param("id")); err != nil {
+			return err
+		}
+
+		existing, err := findPaymentByKey(tx, order.OrderID, operationRefund, key)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if *existing.RefundOfID != input.PaymentID {
+				return errIdempotencyConflict
+			}
+			refund = *existing
+			replayed = true
+			return nil
+		}
+
+		var original models.Payment
+		if err := tx.Preload("Refunds").
+			Where("payment_id = ? AND order_id = ? AND refund_of_id IS NULL AND status = ?",
+				input.PaymentID, order.OrderID, models.PaymentStatusCompleted).
+			First(&original).Error; err != nil {
+			return err
+		}
+
+		remaining := refundable(original)
+		amount := remaining
+		if input.Amount != nil {
+			amount = *input.Amount
+		}
+		if amount.Cmp(remaining) > 0 || !amount.IsPositive() {
+			return fmt.Errorf("%w: %s remaining", errRefundTooLarge, remaining)
+		}
+
+		// A full refund also moves the order to refunded, unless it was already cancelled
+		// or refunded, as when retrying a refund the provider declined
+		markRefunded := remaining.Sub(amount).IsZero() &&
+			order.Status != models.OrderStatusCancelled && order.Status != models.OrderStatusRefunded
+		if markRefunded && !canTransition(order.Status, models.OrderStatusRefunded) {
+			return fmt.Errorf("%w: cannot refund a %s order", errInvalidTransition, order.Status)
+		}
+
+		refund, err = recordRefund(tx, original, amount, key)
+		if err != nil {
+			return err
+		}
+
+		if markRefunded {
+			return transitionOrder(tx, &order, models.OrderStatusRefunded, actorID, input.Reason)
+		}
+		return nil
+	})
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	// Send this refund, and any an earlier request left pending, to the provider
+	settled, err := settleRefunds(c.Request.Context(), config.DB, refund.OrderID)
+	for _, payment := range settled {
+		if payment.PaymentID == refund.PaymentID {
+			refund = payment
+		}
+	}
+	if !replayed {
+		recordAudit(c, "order.refund", "order", refund.OrderID, nil, refund)
+	}
+	if err != nil {
+		respondRefundError(c, err, gin.H{"refund": refund})
+		return
+	}
+
+	status := http.StatusCreated
+	if replayed {
+		status = http.StatusOK
+	}
+	c.JSON(status, refund)
+}
+
+// List the payments and refunds of an order (owner or order managers)
+func GetOrderPayments(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("order_id = ?", c.Param("id"))
+	if !middlewares.HasPermission(c, models.PermissionOrderManage) {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var order models.Order
+	if err := query.Preload("Payments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("payment_date")
+	}).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order.Payments)
+}
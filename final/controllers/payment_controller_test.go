@@ -0,0 +1,135 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"final/config"
+	"final/controllers"
+	"final/models"
+	"final/payments"
+	"final/testdb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pendingOrder creates a pending order of the user totalling total
+func pendingOrder(t *testing.T, tx *gorm.DB, userID uuid.UUID, total models.Money) models.Order {
+	t.Helper()
+	zero := models.NewMoney(0)
+	order := models.Order{
+		UserID:         userID,
+		OrderDate:      time.Now(),
+		Status:         models.OrderStatusPending,
+		Subtotal:       total,
+		DiscountAmount: zero,
+		ShippingAmount: zero,
+		TaxAmount:      zero,
+		TotalAmount:    total,
+		TaxRate:        "0",
+	}
+	if err := tx.Create(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// payOrder pays the order as the user through PayOrder, with handlers running on tx
+func payOrder(t *testing.T, tx *gorm.DB, order models.Order, method string) (int, models.Payment) {
+	t.Helper()
+	previousDB := config.DB
+	config.DB = tx
+	t.Cleanup(func() { config.DB = previousDB })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/orders/:id/pay", func(c *gin.Context) {
+		c.Set("user_id", order.UserID.String())
+	}, controllers.PayOrder)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+order.OrderID.String()+"/pay",
+		strings.NewReader(`{"payment_method": "`+method+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var payment models.Payment
+	json.Unmarshal(w.Body.Bytes(), &payment)
+	return w.Code, payment
+}
+
+// orderStatus reads an order's current status
+func orderStatus(t *testing.T, tx *gorm.DB, orderID uuid.UUID) string {
+	t.Helper()
+	var order models.Order
+	if err := tx.First(&order, "order_id = ?", orderID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return order.Status
+}
+
+func TestPayOrderChargesAndMarksPaid(t *testing.T) {
+	tx := testdb.Open(t)
+	user := testdb.User(t, tx, models.RoleUser)
+	order := pendingOrder(t, tx, user.UserID, models.NewMoney(2500))
+
+	status, payment := payOrder(t, tx, order, "card")
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", status, http.StatusCreated)
+	}
+	if payment.Status != models.PaymentStatusCompleted || payment.Reference == "" {
+		t.Errorf("payment = %+v, want a completed charge with a reference", payment)
+	}
+	if got := orderStatus(t, tx, order.OrderID); got != models.OrderStatusPaid {
+		t.Errorf("order status = %s, want paid", got)
+	}
+}
+
+func TestPayOrderWithNothingToPaySkipsTheProvider(t *testing.T) {
+	tx := testdb.Open(t)
+	user := testdb.User(t, tx, models.RoleUser)
+	order := pendingOrder(t, tx, user.UserID, models.NewMoney(0))
+
+	status, payment := payOrder(t, tx, order, "card")
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", status, http.StatusCreated)
+	}
+	if payment.Status != models.PaymentStatusCompleted || !payment.Amount.IsZero() || payment.Provider != "" {
+		t.Errorf("payment = %+v, want a completed zero payment without a provider", payment)
+	}
+	if got := orderStatus(t, tx, order.OrderID); got != models.OrderStatusPaid {
+		t.Errorf("order status = %s, want paid", got)
+	}
+}
+
+func TestPayOrderDeclinedLeavesOrderPending(t *testing.T) {
+	tx := testdb.Open(t)
+	user := testdb.User(t, tx, models.RoleUser)
+	order := pendingOrder(t, tx, user.UserID, models.NewMoney(2500))
+
+	if status, _ := payOrder(t, tx, order, payments.FakeDeclineMethod); status != http.StatusPaymentRequired {
+		t.Fatalf("status = %d, want %d", status, http.StatusPaymentRequired)
+	}
+	if got := orderStatus(t, tx, order.OrderID); got != models.OrderStatusPending {
+		t.Errorf("order status = %s, want pending", got)
+	}
+	var failed int64
+	if err := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND status = ?", order.OrderID, models.PaymentStatusFailed).
+		Count(&failed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 {
+		t.Errorf("failed charges = %d, want 1", failed)
+	}
+
+	// The customer can try again with another method
+	if status, _ := payOrder(t, tx, order, "card"); status != http.StatusCreated {
+		t.Errorf("retry status = %d, want %d", status, http.StatusCreated)
+	}
+}
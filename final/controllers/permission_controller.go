@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/middlewares"
+	"final/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// errUnknownPermission is returned when granting a permission that does not exist
+	errUnknownPermission = errors.New("permission not found")
+	// errAdminLockout is returned when a change would leave admins unable to manage roles
+	errAdminLockout = errors.New("the admin role must keep role:manage")
+)
+
+// loadRole finds the role named by the :id path parameter
+func loadRole(c *gin.Context, tx *gorm.DB) (models.Role, error) {
+	var role models.Role
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		return role, gorm.ErrRecordNotFound
+	}
+	err := tx.First(&role, "role_id = ?", c.Param("id")).Error
+	return role, err
+}
+
+// respondPermissionError maps errors from role permission changes to responses
+func respondPermissionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case errors.Is(err, errUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Permission not found"})
+	case errors.Is(err, errAdminLockout):
+		c.JSON(http.StatusConflict, gin.H{"error": "The admin role must keep role:manage"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role permissions"})
+	}
+}
+
+// List all permissions
+func GetPermissions(c *gin.Context) {
+	var permissions []models.Permission
+	if err := config.DB.Order("name").Find(&permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// List all roles with their permissions
+func GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Order("role_name").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// Create a role, optionally with an initial set of permissions
+func CreateRole(c *gin.Context) {
+	var input struct {
+		RoleName    string   `json:"role_name" binding:"required,max=50"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Role{}).Where("role_name = ?", input.RoleName).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role name"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A role with this name already exists"})
+		return
+	}
+
+	role := models.Role{RoleName: input.RoleName}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(input.Permissions) > 0 {
+			if err := tx.Where("name IN ?", input.Permissions).Find(&role.Permissions).Error; err != nil {
+				return err
+			}
+			if len(role.Permissions) != len(input.Permissions) {
+				return errUnknownPermission
+			}
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return writeAudit(tx, c, http.StatusCreated, "role.create", "role", role.RoleID,
+			nil, gin.H{"role_name": role.RoleName, "permissions": input.Permissions})
+	})
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+
+	middlewares.InvalidatePermissions()
+	c.JSON(http.StatusCreated, role)
+}
+
+// Grant a permission to a role
+func GrantPermission(c *gin.Context) {
+	var input struct {
+		Permission string `json:"permission" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.Role
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if role, err = loadRole(c, tx); err != nil {
+			return err
+		}
+
+		var permission models.Permission
+		if err := tx.Where("name = ?", input.Permission).First(&permission).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errUnknownPermission
+			}
+			return err
+		}
+
+		if err := tx.Exec("INSERT INTO role_permissions (role_id, permission_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			role.RoleID, permission.PermissionID).Error; err != nil {
+			return err
+		}
+		if err := writeAudit(tx, c, http.StatusOK, "role.permission_grant", "role", role.RoleID,
+			nil, gin.H{"permission": input.Permission}); err != nil {
+			return err
+		}
+		return tx.Preload("Permissions").First(&role, "role_id = ?", role.RoleID).Error
+	})
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+
+	middlewares.InvalidatePermissions()
+	c.JSON(http.StatusOK, role)
+}
+
+// Revoke a permission from a role
+func RevokePermission(c *gin.Context) {
+	var role models.Role
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if role, err = loadRole(c, tx); err != nil {
+			return err
+		}
+
+		name := c.Param("permission")
+		if role.RoleName == models.RoleAdmin && name == models.PermissionRoleManage {
+			return errAdminLockout
+		}
+
+		result := tx.Exec(`DELETE FROM role_permissions
+			WHERE role_id = ? AND permission_id = (SELECT permission_id FROM permissions WHERE name = ?)`,
+			role.RoleID, name)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUnknownPermission
+		}
+		if err := writeAudit(tx, c, http.StatusOK, "role.permission_revoke", "role", role.RoleID,
+			gin.H{"permission": name}, nil); err != nil {
+			return err
+		}
+		return tx.Preload("Permissions").First(&role, "role_id = ?", role.RoleID).Error
+	})
+	if err != nil {
+		respondPermissionError(c, err)
+		return
+	}
+
+	middlewares.InvalidatePermissions()
+	c.JSON(http.StatusOK, role)
+}
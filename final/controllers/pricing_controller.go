@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"final/config"
+	"final/models"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// zipPrefixPattern limits ZIP prefixes to characters that appear in postal codes
+var zipPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9 -]*$`)
+
+// taxRateInput is the payload for creating or updating a tax rate
+type taxRateInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Country     string `json:"country" binding:"required,len=2"`
+	State       string `json:"state" binding:"max=100"`
+	ZipPrefix   string `json:"zip_prefix" binding:"max=20"`
+	Rate        string `json:"rate" binding:"required"`
+	TaxShipping bool   `json:"tax_shipping"`
+}
+
+// apply validates the input and copies it onto a tax rate
+func (input taxRateInput) apply(rate *models.TaxRate) error {
+	fraction, ok := new(big.Rat).SetString(input.Rate)
+	if !ok || strings.Contains(input.Rate, "/") || fraction.Sign() < 0 || fraction.Cmp(big.NewRat(1, 1)) >= 0 {
+		return fmt.Errorf("rate must be a decimal fraction between 0 and 1, e.g. 0.0725")
+	}
+	if !zipPrefixPattern.MatchString(input.ZipPrefix) {
+		return fmt.Errorf("zip_prefix may only contain letters, digits, spaces and dashes")
+	}
+	rate.Name = input.Name
+	rate.Country = strings.ToUpper(input.Country)
+	rate.State = strings.TrimSpace(input.State)
+	rate.ZipPrefix = strings.TrimSpace(input.ZipPrefix)
+	rate.Rate = input.Rate
+	rate.TaxShipping = input.TaxShipping
+	return nil
+}
+
+// taxRegionTaken reports whether another rate already covers the same region
+func taxRegionTaken(db *gorm.DB, rate models.TaxRate) (bool, error) {
+	var count int64
+	err := db.Model(&models.TaxRate{}).
+		Where("country = ? AND state = ? AND zip_prefix = ? AND tax_rate_id <> ?", rate.Country, rate.State, rate.ZipPrefix, rate.TaxRateID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// saveTaxRate validates the input and writes the tax rate
+func saveTaxRate(c *gin.Context, rate *models.TaxRate) bool {
+	var input taxRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := input.apply(rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	taken, err := taxRegionTaken(config.DB, *rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check tax region"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tax rate for this region already exists"})
+		return false
+	}
+
+	if err := config.DB.Save(rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tax rate"})
+		return false
+	}
+	return true
+}
+
+// List tax rates by region
+func GetTaxRates(c *gin.Context) {
+	var rates []models.TaxRate
+	if err := config.DB.Order("country, state, zip_prefix").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tax rates"})
+		return
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+// Create a tax rate
+func CreateTaxRate(c *gin.Context) {
+	var rate models.TaxRate
+	if !saveTaxRate(c, &rate) {
+		return
+	}
+	recordAudit(c, "tax_rate.create", "tax_rate", rate.TaxRateID, nil, rate)
+	c.JSON(http.StatusCreated, rate)
+}
+
+// Update a tax rate
+func UpdateTaxRate(c *gin.Context) {
+	var rate models.TaxRate
+	if err := config.DB.First(&rate, "tax_rate_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+		return
+	}
+	before := rate
+	if !saveTaxRate(c, &rate) {
+		return
+	}
+	recordAudit(c, "tax_rate.update", "tax_rate", rate.TaxRateID, before, rate)
+	c.JSON(http.StatusOK, rate)
+}
+
+// Delete a tax rate
+func DeleteTaxRate(c *gin.Context) {
+	var rate models.TaxRate
+	if err := config.DB.First(&rate, "tax_rate_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+		return
+	}
+	if err := config.DB.Delete(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tax rate"})
+		return
+	}
+	recordAudit(c, "tax_rate.delete", "tax_rate", rate.TaxRateID, rate, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted"})
+}
+
+// shippingTierInput is one tier of a shipping method payload
+type shippingTierInput struct {
+	MinWeightGrams int          `json:"min_weight_grams" binding:"min=0"`
+	MinSubtotal    models.Money `json:"min_subtotal"`
+	Cost           models.Money `json:"cost"`
+}
+
+// shippingMethodInput is the payload for creating or updating a shipping method
+type shippingMethodInput struct {
+	Code          string              `json:"code" binding:"required,max=50"`
+	Name          string              `json:"name" binding:"required,max=100"`
+	Basis         string              `json:"basis" binding:"required,oneof=weight price"`
+	FreeThreshold *models.Money       `json:"free_threshold"`
+	Active        *bool               `json:"active"`
+	Tiers         []shippingTierInput `json:"tiers" binding:"required,min=1,dive"`
+}
+
+// apply validates the input and copies it onto a shipping method
+func (input shippingMethodInput) apply(method *models.ShippingMethod) error {
+	if input.FreeThreshold != nil && input.FreeThreshold.IsNegative() {
+		return fmt.Errorf("free_threshold cannot be negative")
+	}
+	method.Code = strings.ToLower(strings.TrimSpace(input.Code))
+	method.Name = input.Name
+	method.Basis = input.Basis
+	method.FreeThreshold = input.FreeThreshold
+	if input.Active != nil {
+		method.Active = *input.Active
+	}
+
+	method.Tiers = make([]models.ShippingTier, 0, len(input.Tiers))
+	for _, tier := range input.Tiers {
+		if tier.MinSubtotal.IsNegative() || tier.Cost.IsNegative() {
+			return fmt.Errorf("tier amounts cannot be negative")
+		}
+		method.Tiers = append(method.Tiers, models.ShippingTier{
+			MinWeightGrams: tier.MinWeightGrams,
+			MinSubtotal:    tier.MinSubtotal,
+			Cost:           tier.Cost,
+		})
+	}
+	return nil
+}
+
+// shippingCodeTaken reports whether another shipping method already uses the code
+func shippingCodeTaken(db *gorm.DB, code string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.ShippingMethod{}).
+		Where("code = ? AND shipping_method_id <> ?", code, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// saveShippingMethod validates the input and writes the method, replacing its tiers
+func saveShippingMethod(c *gin.Context, method *models.ShippingMethod) bool {
+	var input shippingMethodInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := input.apply(method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	taken, err := shippingCodeTaken(config.DB, method.Code, method.ShippingMethodID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check shipping method code"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "A shipping method with this code already exists"})
+		return false
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tiers").Save(method).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shipping_method_id = ?", method.ShippingMethodID).Delete(&models.ShippingTier{}).Error; err != nil {
+			return err
+		}
+		for i := range method.Tiers {
+			method.Tiers[i].ShippingMethodID = method.ShippingMethodID
+		}
+		return tx.Create(&method.Tiers).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save shipping method"})
+		return false
+	}
+	return true
+}
+
+// listShippingMethods responds with shipping methods and their tiers
+func listShippingMethods(c *gin.Context, activeOnly bool) {
+	query := config.DB.Preload("Tiers").Order("code")
+	if activeOnly {
+		query = query.Where("active = true")
+	}
+
+	var methods []models.ShippingMethod
+	if err := query.Find(&methods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shipping methods"})
+		return
+	}
+	c.JSON(http.StatusOK, methods)
+}
+
+// List the active shipping methods customers can choose from
+func GetShippingMethods(c *gin.Context) {
+	listShippingMethods(c, true)
+}
+
+// List every shipping method, including inactive ones
+func GetAllShippingMethods(c *gin.Context) {
+	listShippingMethods(c, false)
+}
+
+// Create a shipping method; it is active unless "active" is false
+func CreateShippingMethod(c *gin.Context) {
+	method := models.ShippingMethod{Active: true}
+	if !saveShippingMethod(c, &method) {
+		return
+	}
+	recordAudit(c, "shipping_method.create", "shipping_method", method.ShippingMethodID, nil, method)
+	c.JSON(http.StatusCreated, method)
+}
+
+// Update a shipping method, replacing its tiers
+func UpdateShippingMethod(c *gin.Context) {
+	var method models.ShippingMethod
+	if err := config.DB.First(&method, "shipping_method_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		return
+	}
+	before := method
+	if !saveShippingMethod(c, &method) {
+		return
+	}
+	recordAudit(c, "shipping_method.update", "shipping_method", method.ShippingMethodID, before, method)
+	c.JSON(http.StatusOK, method)
+}
+
+// Delete a shipping method; orders keep the code of the method they used
+func DeleteShippingMethod(c *gin.Context) {
+	var method models.ShippingMethod
+	if err := config.DB.First(&method, "shipping_method_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shipping method not found"})
+		return
+	}
+	if err := config.DB.Delete(&method).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shipping method"})
+		return
+	}
+	recordAudit(c, "shipping_method.delete", "shipping_method", method.ShippingMethodID, method, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Shipping method deleted"})
+}
@@ -1,40 +1,173 @@
 package controllers
 
 import (
+	"errors"
+	"final/cache"
 	"final/config"
 	"final/models"
+	"final/wishlists"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// Create a new product
+// validateCategory checks that the product's category exists, writing a 400 response otherwise
+func validateCategory(c *gin.Context, categoryID uuid.UUID) bool {
+	exists, err := categoryExists(config.DB, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
+		return false
+	}
+	return true
+}
+
+// Create a new product with its variants. Without variants the product gets a
+// single default variant holding the given stock; with variants the product's
+// stock is their total and the first is the default unless another is marked.
 func CreateProduct(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	config.DB.Create(&product)
+	if !validateCategory(c, product.CategoryID) {
+		return
+	}
+	if product.Price.IsNegative() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Price cannot be negative"})
+		return
+	}
+	if product.WeightGrams < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Weight cannot be negative"})
+		return
+	}
+	if product.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock cannot be negative"})
+		return
+	}
+
+	variants := product.Variants
+	if len(variants) == 0 {
+		variants = []models.ProductVariant{{Stock: product.Stock}}
+	}
+	hasDefault := false
+	for i := range variants {
+		if variants[i].IsDefault && hasDefault {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only one variant can be the default"})
+			return
+		}
+		hasDefault = hasDefault || variants[i].IsDefault
+	}
+	if !hasDefault {
+		variants[0].IsDefault = true
+	}
+
+	// Variant stock is recorded in the ledger like any other change
+	product.Stock = 0
+	product.Variants = nil
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		for i := range variants {
+			variant := &variants[i]
+			variant.VariantID = uuid.Nil
+			variant.ProductID = product.ProductID
+			// A lone variant may leave its SKU to be derived from the product ID
+			if variant.SKU == "" && len(variants) == 1 {
+				variant.SKU = models.DefaultSKU(product.ProductID)
+			}
+			if err := validateVariant(*variant); err != nil {
+				return err
+			}
+			if err := createVariant(tx, variant, actorID); err != nil {
+				return err
+			}
+			product.Stock += variant.Stock
+		}
+		product.Variants = variants
+		return nil
+	})
+	if err != nil {
+		respondVariantError(c, err, "Failed to create product")
+		return
+	}
+	invalidateProductCache(c)
+	recordAudit(c, "product.create", "product", product.ProductID, nil, product)
 	c.JSON(http.StatusCreated, product)
 }
 
-// Get all products
+// Get products with optional filters, sorting and pagination
 func GetProducts(c *gin.Context) {
+	cacheKey := productListCacheKey(c)
+	if serveCached(c, cacheKey) {
+		return
+	}
+
+	params, err := parseProductListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered := params.applyFilters(config.DB.Model(&models.Product{})).Session(&gorm.Session{})
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
 	var products []models.Product
-	config.DB.Find(&products)
-	c.JSON(http.StatusOK, products)
+	if err := params.applyPage(filtered).Preload("Variants", orderVariants).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	respondCached(c, cacheKey, params.envelope(products, total), cache.ProductsTag)
+}
+
+// productDetail is a product together with its option matrix and rating summary
+type productDetail struct {
+	models.Product
+	// OptionValues lists the values each variant option takes
+	OptionValues map[string][]string
+	ratingSummary
 }
 
 // Get a single product
 func GetProduct(c *gin.Context) {
 	id := c.Param("id")
+	cacheKey := "products:detail:" + id
+	if serveCached(c, cacheKey) {
+		return
+	}
+
 	var product models.Product
-	if err := config.DB.First(&product, "product_id = ?", id).Error; err != nil {
+	if err := config.DB.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, created_at")
+	}).Preload("Variants", orderVariants).First(&product, "product_id = ?", id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
-	c.JSON(http.StatusOK, product)
+
+	summary, err := summarizeRatings(config.DB, product.ProductID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load ratings"})
+		return
+	}
+	respondCached(c, cacheKey, productDetail{Product: product, OptionValues: optionMatrix(product.Variants), ratingSummary: summary}, cache.ProductsTag, productCacheTag(product.ProductID))
 }
 
 // Update a product
@@ -46,21 +179,98 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	before := product
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validateCategory(c, product.CategoryID) {
+		return
+	}
+	if product.Price.IsNegative() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Price cannot be negative"})
+		return
+	}
+	if product.WeightGrams < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Weight cannot be negative"})
+		return
+	}
+	if product.Stock != before.Stock {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock is changed through inventory adjustments"})
+		return
+	}
+	if len(product.Variants) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variants are changed through the product's variant endpoints"})
+		return
+	}
 
-	config.DB.Save(&product)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("stock").Save(&product).Error; err != nil {
+			return err
+		}
+		return wishlists.NotifyPriceDrop(tx, product, nil, before.Price, product.Price)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
+		return
+	}
+	invalidateProductCache(c)
+	recordAudit(c, "product.update", "product", product.ProductID, before, product)
 	c.JSON(http.StatusOK, product)
 }
 
-// Delete a product
+// errProductOrdered is returned when deleting a product that orders still refer to
+var errProductOrdered = errors.New("product has been ordered")
+
+// Delete a product together with its images, reviews and the cart lines holding it.
+// Products that were ever ordered are kept, since their orders refer to them.
 func DeleteProduct(c *gin.Context) {
-	id := c.Param("id")
-	if err := config.DB.Delete(&models.Product{}, "product_id = ?", id).Error; err != nil {
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
+
+	var product models.Product
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// The lock keeps checkout from ordering the product while it is deleted
+		if err := lockProduct(tx, &product, c.Param("id")); err != nil {
+			return err
+		}
+		var ordered int64
+		if err := tx.Model(&models.OrderItem{}).Where("product_id = ?", product.ProductID).Count(&ordered).Error; err != nil {
+			return err
+		}
+		if ordered > 0 {
+			return errProductOrdered
+		}
+
+		if err := tx.Where("product_id = ?", product.ProductID).Find(&product.Images).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.CartItem{}, &models.Review{}, &models.ProductImage{}} {
+			if err := tx.Where("product_id = ?", product.ProductID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&product).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	case errors.Is(err, errProductOrdered):
+		c.JSON(http.StatusConflict, gin.H{"error": "The product has been ordered and cannot be deleted"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
+		return
+	}
+
+	// Stored files are removed only once the rows are gone
+	for _, img := range product.Images {
+		deleteStoredImage(c.Request.Context(), img)
+	}
+	invalidateProductCache(c)
+	recordAudit(c, "product.delete", "product", product.ProductID, product, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted"})
 }
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"final/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// productPriceSQL is a product's lowest effective variant price, which is what the
+// price filters and sort use; it matches models.Product.FromPrice
+const productPriceSQL = "COALESCE((SELECT MIN(COALESCE(v.price, products.price)) FROM product_variants v " +
+	"WHERE v.product_id = products.product_id), products.price)"
+
+// productSortColumns maps the sort query parameter to the expression it sorts on
+var productSortColumns = map[string]string{
+	"name":       "products.name",
+	"price":      productPriceSQL,
+	"stock":      "products.stock",
+	"created_at": "products.created_at",
+}
+
+// productListQueryParams lists the query parameters accepted by product listings
+var productListQueryParams = map[string]bool{
+	"category": true, "min_price": true, "max_price": true, "in_stock": true, "q": true,
+	"sort": true, "order": true, "page": true, "page_size": true, "cursor": true,
+}
+
+// likeEscaper escapes LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// productCursor marks the last product of a page for keyset pagination
+type productCursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v"`
+	ID    uuid.UUID       `json:"id"`
+}
+
+// productListParams holds the filters, sort order and page of a product listing
+type productListParams struct {
+	CategoryID *uuid.UUID
+	MinPrice   *models.Money
+	MaxPrice   *models.Money
+	InStock    bool
+	Search     string
+	Sort       string
+	Desc       bool
+	Page       pagination
+	Cursor     *productCursor
+}
+
+// parseProductListParams reads the product listing query parameters, rejecting invalid values
+func parseProductListParams(c *gin.Context) (productListParams, error) {
+	params := productListParams{Sort: "created_at", Desc: true}
+
+	for name := range c.Request.URL.Query() {
+		if !productListQueryParams[name] {
+			return params, fmt.Errorf("unknown query parameter %q", name)
+		}
+	}
+
+	if raw := c.Query("category"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return params, fmt.Errorf("category must be a valid ID")
+		}
+		params.CategoryID = &id
+	}
+
+	for name, target := range map[string]**models.Money{"min_price": &params.MinPrice, "max_price": &params.MaxPrice} {
+		if raw := c.Query(name); raw != "" {
+			value, err := models.ParseMoney(raw)
+			if err != nil || value.IsNegative() {
+				return params, fmt.Errorf("%s must be a non-negative amount", name)
+			}
+			*target = &value
+		}
+	}
+	if params.MinPrice != nil && params.MaxPrice != nil && params.MinPrice.Cmp(*params.MaxPrice) > 0 {
+		return params, fmt.Errorf("min_price must not exceed max_price")
+	}
+
+	if raw := c.Query("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return params, fmt.Errorf("in_stock must be true or false")
+		}
+		params.InStock = inStock
+	}
+
+	params.Search = strings.TrimSpace(c.Query("q"))
+
+	if raw := c.Query("sort"); raw != "" {
+		if _, ok := productSortColumns[raw]; !ok {
+			return params, fmt.Errorf("sort must be one of name, price, stock, created_at")
+		}
+		params.Sort = raw
+		// Explicit sort fields default to ascending
+		params.Desc = false
+	}
+	if raw := c.Query("order"); raw != "" {
+		switch strings.ToLower(raw) {
+		case "asc":
+			params.Desc = false
+		case "desc":
+			params.Desc = true
+		default:
+			return params, fmt.Errorf("order must be asc or desc")
+		}
+	}
+
+	page, err := parsePagination(c)
+	if err != nil {
+		return params, err
+	}
+	params.Page = page
+
+	if raw := c.Query("cursor"); raw != "" {
+		if c.Query("page") != "" {
+			return params, fmt.Errorf("cursor and page cannot be combined")
+		}
+		cursor, err := decodeProductCursor(raw)
+		if err != nil || cursor.Sort != params.Sort || cursor.Desc != params.Desc {
+			return params, fmt.Errorf("cursor is invalid for this sort order")
+		}
+		params.Cursor = &cursor
+	}
+
+	return params, nil
+}
+
+// applyFilters restricts a product query to the requested filters
+func (p productListParams) applyFilters(db *gorm.DB) *gorm.DB {
+	if p.CategoryID != nil {
+		db = db.Where("products.category_id = ?", *p.CategoryID)
+	}
+	if p.MinPrice != nil {
+		db = db.Where(productPriceSQL+" >= ?", *p.MinPrice)
+	}
+	if p.MaxPrice != nil {
+		db = db.Where(productPriceSQL+" <= ?", *p.MaxPrice)
+	}
+	if p.InStock {
+		db = db.Where("products.stock > 0")
+	}
+	if p.Search != "" {
+		db = db.Where("products.name ILIKE ?", "%"+likeEscaper.Replace(p.Search)+"%")
+	}
+	return db
+}
+
+// applyPage orders the query and restricts it to the requested page or cursor
+func (p productListParams) applyPage(db *gorm.DB) *gorm.DB {
+	column := productSortColumns[p.Sort]
+	direction, comparison := "ASC", ">"
+	if p.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	db = db.Order(fmt.Sprintf("%s %s, products.product_id %s", column, direction, direction))
+	if p.Cursor != nil {
+		db = db.Where(fmt.Sprintf("(%s, products.product_id) %s (?, ?)", column, comparison), p.Cursor.value(), p.Cursor.ID)
+	} else {
+		db = db.Offset(p.Page.Offset())
+	}
+	return db.Limit(p.Page.PageSize)
+}
+
+// nextCursor returns the cursor following the last product, or "" when the page is not full
+func (p productListParams) nextCursor(products []models.Product) string {
+	if len(products) < p.Page.PageSize {
+		return ""
+	}
+	last := products[len(products)-1]
+
+	var value interface{}
+	switch p.Sort {
+	case "name":
+		value = last.Name
+	case "price":
+		value = last.FromPrice()
+	case "stock":
+		value = last.Stock
+	default:
+		value = last.CreatedAt
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	encoded, err := json.Marshal(productCursor{Sort: p.Sort, Desc: p.Desc, Value: raw, ID: last.ProductID})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// envelope wraps a page of products with paging metadata
+func (p productListParams) envelope(products []models.Product, total int64) gin.H {
+	response := gin.H{
+		"data":        products,
+		"page_size":   p.Page.PageSize,
+		"total":       total,
+		"next_cursor": p.nextCursor(products),
+	}
+	if p.Cursor == nil {
+		response["page"] = p.Page.Page
+	}
+	return response
+}
+
+// decodeProductCursor parses a cursor produced by nextCursor
+func decodeProductCursor(raw string) (productCursor, error) {
+	var cursor productCursor
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return cursor, err
+	}
+	if _, ok := productSortColumns[cursor.Sort]; !ok {
+		return cursor, fmt.Errorf("unknown sort field %q", cursor.Sort)
+	}
+	if cursor.value() == nil {
+		return cursor, fmt.Errorf("invalid cursor value")
+	}
+	return cursor, nil
+}
+
+// value decodes the cursor's sort value into a type the database can compare
+func (cursor productCursor) value() interface{} {
+	switch cursor.Sort {
+	case "name":
+		var name string
+		if json.Unmarshal(cursor.Value, &name) != nil {
+			return nil
+		}
+		return name
+	case "price":
+		var price models.Money
+		if json.Unmarshal(cursor.Value, &price) != nil {
+			return nil
+		}
+		return price
+	case "stock":
+		var stock int
+		if json.Unmarshal(cursor.Value, &stock) != nil {
+			return nil
+		}
+		return stock
+	default:
+		var createdAt time.Time
+		if json.Unmarshal(cursor.Value, &createdAt) != nil {
+			return nil
+		}
+		return createdAt
+	}
+}
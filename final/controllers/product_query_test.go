@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"final/models"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// queryContext returns a context for a GET request with the given query string
+func queryContext(query string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/products?"+query, nil)
+	return c
+}
+
+func TestParseProductListParamsDefaults(t *testing.T) {
+	params, err := parseProductListParams(queryContext(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Sort != "created_at" || !params.Desc {
+		t.Errorf("sort = %s desc=%v, want created_at desc", params.Sort, params.Desc)
+	}
+	if params.Page.Page != 1 || params.Page.PageSize != defaultPageSize {
+		t.Errorf("page = %+v, want page 1 of %d", params.Page, defaultPageSize)
+	}
+	if params.CategoryID != nil || params.MinPrice != nil || params.MaxPrice != nil || params.InStock || params.Cursor != nil {
+		t.Errorf("params = %+v, want no filters", params)
+	}
+}
+
+func TestParseProductListParams(t *testing.T) {
+	tests := []struct {
+		query string
+		check func(productListParams) bool
+	}{
+		{"sort=price", func(p productListParams) bool { return p.Sort == "price" && !p.Desc }},
+		{"sort=name&order=DESC", func(p productListParams) bool { return p.Sort == "name" && p.Desc }},
+		{"order=asc", func(p productListParams) bool { return p.Sort == "created_at" && !p.Desc }},
+		{"min_price=5&max_price=10.50", func(p productListParams) bool {
+			return p.MinPrice.Cmp(models.NewMoney(500)) == 0 && p.MaxPrice.Cmp(models.NewMoney(1050)) == 0
+		}},
+		{"min_price=5&max_price=5", func(p productListParams) bool { return p.MinPrice.Cmp(*p.MaxPrice) == 0 }},
+		{"in_stock=true", func(p productListParams) bool { return p.InStock }},
+		{"q=+lamp+", func(p productListParams) bool { return p.Search == "lamp" }},
+		{"page=3&page_size=10", func(p productListParams) bool { return p.Page.Offset() == 20 && p.Page.PageSize == 10 }},
+	}
+	for _, tt := range tests {
+		params, err := parseProductListParams(queryContext(tt.query))
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if !tt.check(params) {
+			t.Errorf("%s: unexpected params %+v", tt.query, params)
+		}
+	}
+}
+
+func TestParseProductListParamsRejectsInvalidValues(t *testing.T) {
+	for _, query := range []string{
+		"color=red",
+		"category=not-an-id",
+		"min_price=-1",
+		"max_price=abc",
+		"min_price=10&max_price=5",
+		"min_price=1.005",
+		"in_stock=maybe",
+		"sort=rating",
+		"order=up",
+		"page=0",
+		"page_size=101",
+		"cursor=not-a-cursor",
+		"cursor=abc&page=2",
+	} {
+		if _, err := parseProductListParams(queryContext(query)); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestProductCursorRoundTrip(t *testing.T) {
+	params := productListParams{Sort: "price", Page: pagination{Page: 1, PageSize: 2}}
+	last := models.Product{ProductID: uuid.New(), Price: models.NewMoney(1999)}
+	cursor := params.nextCursor([]models.Product{{ProductID: uuid.New()}, last})
+	if cursor == "" {
+		t.Fatal("nextCursor of a full page is empty")
+	}
+
+	parsed, err := parseProductListParams(queryContext("sort=price&cursor=" + cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Cursor.ID != last.ProductID {
+		t.Errorf("cursor ID = %s, want %s", parsed.Cursor.ID, last.ProductID)
+	}
+	if price, ok := parsed.Cursor.value().(models.Money); !ok || price.Cmp(last.Price) != 0 {
+		t.Errorf("cursor value = %v, want %s", parsed.Cursor.value(), last.Price)
+	}
+
+	// A cursor only continues the sort order it was made for
+	if _, err := parseProductListParams(queryContext("sort=price&order=desc&cursor=" + cursor)); err == nil {
+		t.Error("cursor accepted for a different order")
+	}
+	if _, err := parseProductListParams(queryContext("sort=name&cursor=" + cursor)); err == nil {
+		t.Error("cursor accepted for a different sort field")
+	}
+}
+
+func TestNextCursorIsEmptyForLastPage(t *testing.T) {
+	params := productListParams{Sort: "name", Page: pagination{Page: 1, PageSize: 2}}
+	if cursor := params.nextCursor([]models.Product{{Name: "Lamp"}}); cursor != "" {
+		t.Errorf("nextCursor of a partial page = %q, want empty", cursor)
+	}
+}
+
+func TestPriceCursorUsesLowestVariantPrice(t *testing.T) {
+	params := productListParams{Sort: "price", Page: pagination{Page: 1, PageSize: 1}}
+	override := models.NewMoney(1500)
+	last := models.Product{ProductID: uuid.New(), Price: models.NewMoney(1999), Variants: []models.ProductVariant{
+		{IsDefault: true},
+		{Price: &override},
+	}}
+
+	parsed, err := parseProductListParams(queryContext("sort=price&cursor=" + params.nextCursor([]models.Product{last})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price, ok := parsed.Cursor.value().(models.Money); !ok || price.Cmp(override) != 0 {
+		t.Errorf("cursor value = %v, want %s", parsed.Cursor.value(), override)
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/models"
+	"final/pricing"
+	"final/promotions"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// pricingLine converts a product variant and quantity into a line for the pricing calculators
+func pricingLine(product models.Product, variant models.ProductVariant, quantity int) pricing.Line {
+	return pricing.Line{
+		ProductID:   product.ProductID,
+		Name:        variant.Label(product),
+		Quantity:    quantity,
+		Price:       variant.EffectivePrice(product),
+		WeightGrams: variant.EffectiveWeightGrams(product),
+	}
+}
+
+// applyQuote copies a quote's amounts and the choices behind them onto an order
+func applyQuote(order *models.Order, quote pricing.Quote) {
+	order.Subtotal = quote.Subtotal
+	order.DiscountAmount = quote.Discount
+	order.ShippingAmount = quote.Shipping
+	order.ShippingMethod = quote.ShippingMethod
+	order.TaxAmount = quote.Tax
+	order.TaxRegion = quote.TaxRegion
+	order.TaxRate = quote.TaxRate
+	order.TotalAmount = quote.Total
+}
+
+// Quote the current cart with a line-item breakdown of discount, shipping and tax.
+// It takes the same choices as checkout and places no order.
+func GetCartQuote(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ShippingAddressID *uuid.UUID `json:"shipping_address_id"`
+		CouponCode        *string    `json:"coupon_code" binding:"omitempty,max=50"`
+		ShippingMethod    string     `json:"shipping_method" binding:"max=50"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cart, _, err := loadCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+	if len(cart.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+
+	shipping, err := findCheckoutAddress(config.DB, userID, input.ShippingAddressID, "is_default_shipping")
+	if errors.Is(err, errAddressNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Address not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load address"})
+		return
+	}
+
+	req := pricing.Request{ShippingMethod: input.ShippingMethod}
+	if shipping != nil {
+		req.Address = snapshotAddress(*shipping)
+	}
+	for _, item := range cart.Items {
+		req.Lines = append(req.Lines, pricingLine(item.Product, item.Variant, item.Quantity))
+	}
+
+	code := input.CouponCode
+	if code == nil {
+		code = cart.CouponCode
+	}
+	if code != nil && *code != "" {
+		coupon, err := promotions.Find(config.DB, *code)
+		var applied promotions.Quote
+		if err == nil {
+			applied, err = promotions.Apply(config.DB, coupon, userID, cartLines(cart.Items), time.Now())
+		}
+		if errors.Is(err, promotions.ErrInvalidCoupon) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+			return
+		}
+		req.Discount = applied.Discount
+	}
+
+	quote, err := pricing.Calculate(config.DB, req)
+	if errors.Is(err, pricing.ErrShippingUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to quote cart"})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
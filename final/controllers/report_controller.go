@@ -1,24 +1,385 @@
 package controllers
 
 import (
+	"encoding/csv"
 	"final/config"
+	"final/inventory"
+	"final/models"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// Get total sales per product
+const (
+	// defaultTopProducts is how many products the top products report lists by default
+	defaultTopProducts = 10
+	// maxTopProducts caps the limit parameter of the top products report
+	maxTopProducts = 100
+)
+
+// reportExcludedStatuses are order statuses that do not count as sales
+var reportExcludedStatuses = []string{models.OrderStatusCancelled, models.OrderStatusRefunded}
+
+// Sales are net merchandise revenue: item prices less coupon discounts, without
+// shipping and tax, which the store collects but does not earn from products
+const (
+	// orderNetSales is an order's net merchandise revenue
+	orderNetSales = "(orders.subtotal - orders.discount_amount)"
+	// itemNetSales is an order item's net merchandise revenue
+	itemNetSales = "(order_items.price * order_items.quantity - order_items.discount_amount)"
+)
+
+// reportPeriods maps the period groupings to their date_trunc field
+var reportPeriods = map[string]string{
+	"day":   "day",
+	"week":  "week",
+	"month": "month",
+}
+
+// reportRange is the order date range of a report; a nil bound is open
+type reportRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// parseReportTime accepts an RFC 3339 time or a YYYY-MM-DD date. A date used as
+// the upper bound covers the whole day.
+func parseReportTime(raw string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return t, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseReportRange reads the from and to query parameters
+func parseReportRange(c *gin.Context) (reportRange, error) {
+	var r reportRange
+	if raw := c.Query("from"); raw != "" {
+		from, err := parseReportTime(raw, false)
+		if err != nil {
+			return r, fmt.Errorf("from must be a date (YYYY-MM-DD) or an RFC 3339 time")
+		}
+		r.From = &from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, err := parseReportTime(raw, true)
+		if err != nil {
+			return r, fmt.Errorf("to must be a date (YYYY-MM-DD) or an RFC 3339 time")
+		}
+		r.To = &to
+	}
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return r, fmt.Errorf("from must be before to")
+	}
+	return r, nil
+}
+
+// salesOrders returns the orders that count as sales within the range
+func (r reportRange) salesOrders(db *gorm.DB) *gorm.DB {
+	query := db.Where("orders.status NOT IN ?", reportExcludedStatuses)
+	if r.From != nil {
+		query = query.Where("orders.order_date >= ?", *r.From)
+	}
+	if r.To != nil {
+		query = query.Where("orders.order_date < ?", *r.To)
+	}
+	return query
+}
+
+// salesSummary totals the orders in a report
+type salesSummary struct {
+	OrderCount        int64
+	TotalSales        models.Money
+	AverageOrderValue models.Money
+}
+
+// productSales is one product's line in a report
+type productSales struct {
+	ProductID   uuid.UUID
+	ProductName string
+	Quantity    int64
+	OrderCount  int64
+	TotalSales  models.Money
+}
+
+// categorySales is one category's line in a report
+type categorySales struct {
+	CategoryID   uuid.UUID
+	CategoryName string
+	Quantity     int64
+	OrderCount   int64
+	TotalSales   models.Money
+}
+
+// periodSales is one day, week or month in a report
+type periodSales struct {
+	Period            time.Time
+	OrderCount        int64
+	TotalSales        models.Money
+	AverageOrderValue models.Money
+}
+
+// summarizeSales totals the net sales of the orders in the range
+func summarizeSales(r reportRange) (salesSummary, error) {
+	var summary salesSummary
+	err := r.salesOrders(config.DB.Model(&models.Order{})).
+		Select(`COUNT(*) AS order_count,
+			COALESCE(SUM(` + orderNetSales + `), 0) AS total_sales,
+			COALESCE(AVG(` + orderNetSales + `), 0) AS average_order_value`).
+		Scan(&summary).Error
+	return summary, err
+}
+
+// salesByProduct groups sold items by product, best sellers first. Grouping is by
+// product_id so different products that share a name are reported separately.
+func salesByProduct(r reportRange, limit int) ([]productSales, error) {
+	rows := []productSales{}
+	query := r.salesOrders(config.DB.Table("order_items")).
+		Select(`order_items.product_id,
+			MAX(products.name) AS product_name,
+			SUM(order_items.quantity) AS quantity,
+			COUNT(DISTINCT order_items.order_id) AS order_count,
+			SUM(` + itemNetSales + `) AS total_sales`).
+		Joins("JOIN orders ON orders.order_id = order_items.order_id").
+		Joins("LEFT JOIN products ON products.product_id = order_items.product_id").
+		Group("order_items.product_id").
+		Order("total_sales DESC, order_items.product_id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&rows).Error
+	return rows, err
+}
+
+// salesByCategory groups sold items by the current category of their product
+func salesByCategory(r reportRange) ([]categorySales, error) {
+	rows := []categorySales{}
+	err := r.salesOrders(config.DB.Table("order_items")).
+		Select(`categories.category_id,
+			MAX(categories.name) AS category_name,
+			SUM(order_items.quantity) AS quantity,
+			COUNT(DISTINCT order_items.order_id) AS order_count,
+			SUM(` + itemNetSales + `) AS total_sales`).
+		Joins("JOIN orders ON orders.order_id = order_items.order_id").
+		Joins("JOIN products ON products.product_id = order_items.product_id").
+		Joins("JOIN categories ON categories.category_id = products.category_id").
+		Group("categories.category_id").
+		Order("total_sales DESC, categories.category_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// salesByPeriod groups orders by the day, week or month they were placed
+func salesByPeriod(r reportRange, period string) ([]periodSales, error) {
+	rows := []periodSales{}
+	err := r.salesOrders(config.DB.Model(&models.Order{})).
+		Select(`date_trunc(?, order_date) AS period,
+			COUNT(*) AS order_count,
+			SUM(`+orderNetSales+`) AS total_sales,
+			AVG(`+orderNetSales+`) AS average_order_value`, reportPeriods[period]).
+		Group("period").
+		Order("period").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// lowStockProduct is one product variant in the low-stock report
+type lowStockProduct struct {
+	ProductID    uuid.UUID
+	ProductName  string
+	CategoryName string
+	VariantID    uuid.UUID
+	SKU          string
+	Options      models.JSONMap
+	Stock        int
+	Threshold    int
+}
+
+// lowStockProducts lists variants at or below their product's low-stock threshold, emptiest first
+func lowStockProducts() ([]lowStockProduct, error) {
+	rows := []lowStockProduct{}
+	err := config.DB.Table("product_variants").
+		Select(`products.product_id,
+			products.name AS product_name,
+			categories.name AS category_name,
+			product_variants.variant_id,
+			product_variants.sku,
+			product_variants.options,
+			product_variants.stock,
+			COALESCE(products.low_stock_threshold, ?) AS threshold`, inventory.LowStockThreshold).
+		Joins("JOIN products ON products.product_id = product_variants.product_id").
+		Joins("LEFT JOIN categories ON categories.category_id = products.category_id").
+		Where("product_variants.stock <= COALESCE(products.low_stock_threshold, ?)", inventory.LowStockThreshold).
+		Order("product_variants.stock, products.name, product_variants.sku").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// wantsCSV reports whether the client asked for CSV with ?format=csv or the Accept header
+func wantsCSV(c *gin.Context) bool {
+	if format := c.Query("format"); format != "" {
+		return format == "csv"
+	}
+	return c.NegotiateFormat(gin.MIMEJSON, "text/csv") == "text/csv"
+}
+
+// writeCSV sends rows as a CSV attachment
+func writeCSV(c *gin.Context, filename string, header []string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(header)
+	writer.WriteAll(rows)
+}
+
+// Get sales within an optional from/to range, grouped by product (default),
+// category, day, week or month, as JSON or CSV. Sales are net of coupon discounts
+// and exclude shipping and tax; cancelled and refunded orders are not counted.
 func GetSalesReport(c *gin.Context) {
-	var report []struct {
-		ProductName string
-		TotalSales  float64
+	r, err := parseReportRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "product")
+
+	summary, err := summarizeSales(r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sales report"})
+		return
+	}
+
+	var rows interface{}
+	var header []string
+	var records [][]string
+	switch {
+	case groupBy == "product":
+		products, err := salesByProduct(r, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sales report"})
+			return
+		}
+		rows = products
+		header = []string{"product_id", "product_name", "quantity", "order_count", "total_sales"}
+		for _, row := range products {
+			records = append(records, []string{row.ProductID.String(), row.ProductName,
+				strconv.FormatInt(row.Quantity, 10), strconv.FormatInt(row.OrderCount, 10), row.TotalSales.String()})
+		}
+	case groupBy == "category":
+		categories, err := salesByCategory(r)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sales report"})
+			return
+		}
+		rows = categories
+		header = []string{"category_id", "category_name", "quantity", "order_count", "total_sales"}
+		for _, row := range categories {
+			records = append(records, []string{row.CategoryID.String(), row.CategoryName,
+				strconv.FormatInt(row.Quantity, 10), strconv.FormatInt(row.OrderCount, 10), row.TotalSales.String()})
+		}
+	case reportPeriods[groupBy] != "":
+		periods, err := salesByPeriod(r, groupBy)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build sales report"})
+			return
+		}
+		rows = periods
+		header = []string{"period", "order_count", "total_sales", "average_order_value"}
+		for _, row := range periods {
+			records = append(records, []string{row.Period.Format(time.RFC3339), strconv.FormatInt(row.OrderCount, 10),
+				row.TotalSales.String(), row.AverageOrderValue.String()})
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be one of product, category, day, week, month"})
+		return
 	}
 
-	config.DB.Table("order_items").
-		Select("products.name AS product_name, SUM(order_items.price * order_items.quantity) AS total_sales").
-		Joins("JOIN products ON order_items.product_id = products.product_id").
-		Group("products.name").
-		Scan(&report)
+	if wantsCSV(c) {
+		writeCSV(c, "sales-by-"+groupBy+".csv", header, records)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":     r.From,
+		"to":       r.To,
+		"group_by": groupBy,
+		"summary":  summary,
+		"data":     rows,
+	})
+}
+
+// Get the best-selling products within an optional from/to range, as JSON or CSV
+func GetTopProductsReport(c *gin.Context) {
+	r, err := parseReportRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := defaultTopProducts
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxTopProducts {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxTopProducts)})
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, report)
+	products, err := salesByProduct(r, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build top products report"})
+		return
+	}
+
+	if wantsCSV(c) {
+		records := make([][]string, 0, len(products))
+		for i, row := range products {
+			records = append(records, []string{strconv.Itoa(i + 1), row.ProductID.String(), row.ProductName,
+				strconv.FormatInt(row.Quantity, 10), strconv.FormatInt(row.OrderCount, 10), row.TotalSales.String()})
+		}
+		writeCSV(c, "top-products.csv", []string{"rank", "product_id", "product_name", "quantity", "order_count", "total_sales"}, records)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":  r.From,
+		"to":    r.To,
+		"limit": limit,
+		"data":  products,
+	})
+}
+
+// Get the product variants at or below their low-stock threshold, as JSON or CSV
+func GetLowStockReport(c *gin.Context) {
+	products, err := lowStockProducts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build low stock report"})
+		return
+	}
+
+	if wantsCSV(c) {
+		records := make([][]string, 0, len(products))
+		for _, row := range products {
+			records = append(records, []string{row.ProductID.String(), row.ProductName, row.CategoryName,
+				row.VariantID.String(), row.SKU, strconv.Itoa(row.Stock), strconv.Itoa(row.Threshold)})
+		}
+		writeCSV(c, "low-stock.csv", []string{"product_id", "product_name", "category_name", "variant_id", "sku", "stock", "threshold"}, records)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"default_threshold": inventory.LowStockThreshold,
+		"data":              products,
+	})
 }
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"final/cache"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// productCacheTag is attached to the cached detail response of one product
+func productCacheTag(productID uuid.UUID) string {
+	return "product:" + productID.String()
+}
+
+// productListCacheKey identifies a product list response by its query string, hashed
+// to keep keys short; Encode sorts the parameters so equivalent queries share a key
+func productListCacheKey(c *gin.Context) string {
+	sum := sha256.Sum256([]byte(c.Request.URL.Query().Encode()))
+	return "products:list:" + hex.EncodeToString(sum[:])
+}
+
+// serveCached writes a cached JSON response and reports whether there was one.
+// Cache errors are logged and treated as misses so a cache outage never fails a request.
+func serveCached(c *gin.Context, key string) bool {
+	if cache.Default == nil {
+		return false
+	}
+	body, ok, err := cache.Default.Get(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("Cache get %s failed: %v", key, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	c.Header("X-Cache", "HIT")
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	return true
+}
+
+// respondCached writes a 200 JSON response and stores it in the cache under key and tags
+func respondCached(c *gin.Context, key string, value interface{}, tags ...string) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	if cache.Default != nil {
+		if err := cache.Default.Set(c.Request.Context(), key, body, cache.TTL, tags...); err != nil {
+			log.Printf("Cache set %s failed: %v", key, err)
+		}
+		c.Header("X-Cache", "MISS")
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// invalidateProductCache drops cached product responses after a change has been committed
+func invalidateProductCache(c *gin.Context, tags ...string) {
+	if cache.Default == nil {
+		return
+	}
+	if len(tags) == 0 {
+		tags = []string{cache.ProductsTag}
+	}
+	if err := cache.Default.InvalidateTags(c.Request.Context(), tags...); err != nil {
+		log.Printf("Cache invalidation of %v failed: %v", tags, err)
+	}
+}
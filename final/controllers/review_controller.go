@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"final/config"
+	"final/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reviewInput is the payload for creating or editing a review
+type reviewInput struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=5000"`
+}
+
+// ratingSummary aggregates the reviews of a product
+type ratingSummary struct {
+	AverageRating   float64
+	RatingCount     int64
+	RatingHistogram map[int]int64
+}
+
+// summarizeRatings computes the average rating and the count per star for a product
+func summarizeRatings(db *gorm.DB, productID uuid.UUID) (ratingSummary, error) {
+	summary := ratingSummary{RatingHistogram: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	if err := db.Model(&models.Review{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ?", productID).
+		Group("rating").
+		Scan(&rows).Error; err != nil {
+		return summary, err
+	}
+
+	var total int64
+	for _, row := range rows {
+		summary.RatingHistogram[row.Rating] = row.Count
+		summary.RatingCount += row.Count
+		total += int64(row.Rating) * row.Count
+	}
+	if summary.RatingCount > 0 {
+		summary.AverageRating = float64(total) / float64(summary.RatingCount)
+	}
+	return summary, nil
+}
+
+// hasDeliveredProduct reports whether the user received the product in a delivered order
+func hasDeliveredProduct(db *gorm.DB, userID, productID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Table("order_items").
+		Joins("JOIN orders ON orders.order_id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?",
+			userID, models.OrderStatusDelivered, productID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// List the reviews of a product, newest first
+func GetProductReviews(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := config.DB.Model(&models.Review{}).Where("product_id = ?", c.Param("id")).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	var reviews []models.Review
+	if err := query.Order("created_at DESC").
+		Offset(page.Offset()).
+		Limit(page.PageSize).
+		Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      reviews,
+		"page":      page.Page,
+		"page_size": page.PageSize,
+		"total":     total,
+	})
+}
+
+// Review a product the user has received
+func CreateReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input reviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, "product_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	delivered, err := hasDeliveredProduct(config.DB, userID, product.ProductID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check order history"})
+		return
+	}
+	if !delivered {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only review products from a delivered order"})
+		return
+	}
+
+	// idx_reviews_product_user turns a second review of the product, including one
+	// created concurrently, into a no-op
+	review := models.Review{
+		ProductID: product.ProductID,
+		UserID:    userID,
+		Rating:    input.Rating,
+		Comment:   input.Comment,
+	}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&review)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this product"})
+		return
+	}
+
+	invalidateProductCache(c, productCacheTag(review.ProductID))
+	c.JSON(http.StatusCreated, review)
+}
+
+// Edit one of the current user's reviews
+func UpdateReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input reviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.Where("review_id = ? AND user_id = ?", c.Param("id"), userID).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	review.Rating = input.Rating
+	review.Comment = input.Comment
+	if err := config.DB.Model(&review).Updates(map[string]interface{}{
+		"rating":  review.Rating,
+		"comment": review.Comment,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
+		return
+	}
+
+	invalidateProductCache(c, productCacheTag(review.ProductID))
+	c.JSON(http.StatusOK, review)
+}
+
+// Delete one of the current user's reviews
+func DeleteReview(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.Where("review_id = ? AND user_id = ?", c.Param("id"), userID).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	if err := config.DB.Delete(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+		return
+	}
+
+	invalidateProductCache(c, productCacheTag(review.ProductID))
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/middlewares"
+	"final/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errUnknownRole is returned when assigning a role that does not exist
+	errUnknownRole = errors.New("role not found")
+	// errLastAdmin is returned when a change would leave no admin account
+	errLastAdmin = errors.New("cannot remove the last admin")
+	// errOwnRole is returned when a user tries to change their own role
+	errOwnRole = errors.New("cannot change your own role")
+	// errRoleNotGrantable is returned when a role grants permissions the caller lacks
+	errRoleNotGrantable = errors.New("role grants permissions you do not have")
+)
+
+// canGrant reports whether the caller may hand out a role. Without role:manage a
+// caller can only grant roles whose permissions they already hold.
+func canGrant(c *gin.Context, role models.Role) bool {
+	if middlewares.HasPermission(c, models.PermissionRoleManage) {
+		return true
+	}
+	for _, permission := range role.Permissions {
+		if !middlewares.HasPermission(c, permission.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// changeUserRole assigns a role to a user, revokes their sessions so the change
+// takes effect immediately, and writes the change to the audit log with it
+func changeUserRole(c *gin.Context, roleName string) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	previous := ""
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Every role change locks the admins first, in a fixed order, so two
+		// concurrent demotions cannot each count the other as the remaining admin
+		var adminIDs []uuid.UUID
+		if err := tx.Model(&models.User{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "users"}}).
+			Joins("JOIN roles ON roles.role_id = users.role_id").
+			Where("roles.role_name = ?", models.RoleAdmin).
+			Order("users.user_id").
+			Pluck("users.user_id", &adminIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Role").
+			First(&user, "user_id = ?", c.Param("id")).Error; err != nil {
+			return err
+		}
+		if user.UserID == actorID {
+			return errOwnRole
+		}
+
+		var role models.Role
+		if err := tx.Preload("Permissions").Where("role_name = ?", roleName).First(&role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errUnknownRole
+			}
+			return err
+		}
+		if role.RoleID == user.RoleID {
+			return nil
+		}
+		if !canGrant(c, role) {
+			return errRoleNotGrantable
+		}
+
+		if user.Role.RoleName == models.RoleAdmin && len(adminIDs) <= 1 {
+			return errLastAdmin
+		}
+
+		previous = user.Role.RoleName
+		if err := tx.Model(&user).Update("role_id", role.RoleID).Error; err != nil {
+			return err
+		}
+		if err := revokeSessions(tx, "user_id = ?", user.UserID); err != nil {
+			return err
+		}
+		user.Role = role
+		return writeAudit(tx, c, http.StatusOK, "user.role_change", "user", user.UserID,
+			gin.H{"role": previous}, gin.H{"role": role.RoleName})
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case errors.Is(err, errUnknownRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found"})
+		return
+	case errors.Is(err, errLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot remove the last admin"})
+		return
+	case errors.Is(err, errOwnRole), errors.Is(err, errRoleNotGrantable):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": user.UserID, "role": user.Role.RoleName})
+}
+
+// Grant a role to a user (admin only)
+func UpdateUserRole(c *gin.Context) {
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	changeUserRole(c, input.Role)
+}
+
+// Revoke a user's role, returning them to a regular user (admin only)
+func RevokeUserRole(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	changeUserRole(c, models.RoleUser)
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"final/config"
+	"final/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// searchHeadlineOptions configures the highlighted snippets returned by ts_headline
+	searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=10"
+	// trigramThreshold is the minimum name similarity for typo-tolerant matches
+	trigramThreshold = 0.2
+)
+
+// escapeHTMLSQL wraps a SQL text expression so it yields HTML-escaped text. Highlights
+// are built over the escaped text, leaving <mark> as the only markup in the result.
+func escapeHTMLSQL(expr string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"''", "&#39;"}} {
+		expr = "replace(" + expr + ", '" + r[0] + "', '" + r[1] + "')"
+	}
+	return expr
+}
+
+// loadVariants attaches each result's variants, as the product list endpoints preload them
+func loadVariants(db *gorm.DB, results []productSearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(results))
+	for i, result := range results {
+		ids[i] = result.ProductID
+	}
+	var variants []models.ProductVariant
+	if err := orderVariants(db).Where("product_id IN ?", ids).Find(&variants).Error; err != nil {
+		return err
+	}
+	byProduct := make(map[uuid.UUID][]models.ProductVariant, len(results))
+	for _, variant := range variants {
+		byProduct[variant.ProductID] = append(byProduct[variant.ProductID], variant)
+	}
+	for i := range results {
+		results[i].Variants = byProduct[results[i].ProductID]
+	}
+	return nil
+}
+
+// productSearchResult is a product with its relevance and highlighted text
+type productSearchResult struct {
+	models.Product
+	Rank          float64
+	NameHighlight string
+	Snippet       string
+}
+
+// searchProducts runs a search query and returns one page of results with the total match count
+func searchProducts(base *gorm.DB, selectSQL string, selectArgs []interface{}, params productListParams, sorted bool) ([]productSearchResult, int64, error) {
+	base = base.Session(&gorm.Session{})
+
+	var total int64
+	if err := base.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := base.Select(selectSQL, selectArgs...)
+	if sorted {
+		query = params.applyPage(query)
+	} else {
+		query = query.Order("rank DESC, products.product_id").Offset(params.Page.Offset()).Limit(params.Page.PageSize)
+	}
+
+	var results []productSearchResult
+	if err := query.Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+	return results, total, loadVariants(config.DB, results)
+}
+
+// Search products by words in their name and description
+func SearchProducts(c *gin.Context) {
+	params, err := parseProductListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// q is the search query here rather than a name filter
+	term := normalizeSearchTerm(params.Search)
+	params.Search = ""
+	if term == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	// Results are ranked by relevance unless a sort field is given; cursors need a sort field
+	sorted := c.Query("sort") != ""
+	if params.Cursor != nil && !sorted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor requires a sort field"})
+		return
+	}
+
+	fullText := params.applyFilters(config.DB.Table("products").
+		Joins("CROSS JOIN websearch_to_tsquery('english', ?) AS tsq", term).
+		Where("products.search_vector @@ tsq"))
+	results, total, err := searchProducts(fullText,
+		"products.*, ts_rank(products.search_vector, tsq) AS rank, "+
+			"ts_headline('english', "+escapeHTMLSQL("products.name")+", tsq, ?) AS name_highlight, "+
+			"ts_headline('english', "+escapeHTMLSQL("coalesce(products.description, '')")+", tsq, ?) AS snippet",
+		[]interface{}{searchHeadlineOptions, searchHeadlineOptions}, params, sorted)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search products"})
+		return
+	}
+
+	// Fall back to trigram similarity when no word matched, which catches typos
+	fuzzy := false
+	if total == 0 && params.Cursor == nil {
+		fuzzy = true
+		trigram := params.applyFilters(config.DB.Table("products").
+			Where("similarity(products.name, ?) > ?", term, trigramThreshold))
+		results, total, err = searchProducts(trigram,
+			"products.*, similarity(products.name, ?) AS rank, "+escapeHTMLSQL("products.name")+" AS name_highlight, "+
+				escapeHTMLSQL("left(coalesce(products.description, ''), 200)")+" AS snippet",
+			[]interface{}{term}, params, sorted)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search products"})
+			return
+		}
+	}
+
+	response := gin.H{
+		"data":      results,
+		"page_size": params.Page.PageSize,
+		"total":     total,
+		"fuzzy":     fuzzy,
+	}
+	if params.Cursor == nil {
+		response["page"] = params.Page.Page
+	}
+	if sorted {
+		products := make([]models.Product, len(results))
+		for i, result := range results {
+			products[i] = result.Product
+		}
+		response["next_cursor"] = params.nextCursor(products)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// normalizeSearchTerm collapses whitespace in a search query
+func normalizeSearchTerm(term string) string {
+	return strings.Join(strings.Fields(term), " ")
+}
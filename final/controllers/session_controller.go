@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/models"
+	"final/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sessionTTL is how long a session can be kept alive with refresh tokens
+const sessionTTL = 30 * 24 * time.Hour
+
+// errInvalidRefreshToken is returned for unknown, reused or expired refresh tokens
+var errInvalidRefreshToken = errors.New("invalid refresh token")
+
+// issueTokens creates a new refresh token for the session and a matching access token
+func issueTokens(tx *gorm.DB, user models.User, session models.Session) (gin.H, error) {
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&models.RefreshToken{
+		SessionID: session.SessionID,
+		TokenHash: utils.HashToken(refreshToken),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWT(user.UserID.String(), user.Role.RoleName, session.SessionID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// startSession creates a session for a user who just logged in and returns its tokens
+func startSession(c *gin.Context, user models.User) (gin.H, error) {
+	var tokens gin.H
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		session := models.Session{
+			UserID:    user.UserID,
+			UserAgent: truncate(c.Request.UserAgent(), 255),
+			IPAddress: c.ClientIP(),
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(sessionTTL),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, user, session)
+		return err
+	})
+	return tokens, err
+}
+
+// revokeSessions marks the matching active sessions as revoked
+func revokeSessions(db *gorm.DB, query string, args ...interface{}) error {
+	return db.Model(&models.Session{}).
+		Where("revoked_at IS NULL").
+		Where(query, args...).
+		Update("revoked_at", time.Now()).Error
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// Exchange a refresh token for a new access token and refresh token
+func RefreshSession(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tokens gin.H
+	reused := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the token so concurrent refreshes with the same token cannot both succeed
+		var stored models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(input.RefreshToken)).
+			First(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errInvalidRefreshToken
+			}
+			return err
+		}
+
+		var session models.Session
+		if err := tx.Preload("User.Role").First(&session, "session_id = ?", stored.SessionID).Error; err != nil {
+			return err
+		}
+		if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			return errInvalidRefreshToken
+		}
+
+		// A token that was already rotated is being replayed: assume it leaked and end the session
+		if stored.UsedAt != nil {
+			reused = true
+			return revokeSessions(tx, "session_id = ?", session.SessionID)
+		}
+
+		if err := tx.Model(&stored).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, session.User, session)
+		return err
+	})
+
+	switch {
+	case reused:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected; session revoked"})
+		return
+	case errors.Is(err, errInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Log out of the current session
+func Logout(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(fmt.Sprint(c.MustGet("session_id")))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
+		return
+	}
+
+	if err := revokeSessions(config.DB, "session_id = ? AND user_id = ?", sessionID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// Log out of every session of the current user
+func LogoutAll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := revokeSessions(config.DB, "user_id = ?", userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all devices"})
+}
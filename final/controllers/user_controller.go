@@ -1,11 +1,10 @@
 package controllers
 
 import (
+	"final/audit"
 	"final/config"
 	"final/models"
-	"final/utils"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,7 +17,6 @@ func Register(c *gin.Context) {
 		Username string `json:"username" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6"`
-		Role     string `json:"role" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -26,16 +24,9 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Validate role (must be either "user" or "admin")
-	input.Role = strings.ToLower(input.Role) // Normalize role to lowercase
-	if input.Role != "user" && input.Role != "admin" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role. Must be 'user' or 'admin'"})
-		return
-	}
-
-	// Fetch RoleID from the Role table
+	// Public registration always creates a regular user; admins grant other roles
 	var role models.Role
-	if err := config.DB.Where("role_name = ?", input.Role).First(&role).Error; err != nil {
+	if err := config.DB.Where("role_name = ?", models.RoleUser).First(&role).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found"})
 		return
 	}
@@ -61,7 +52,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully", "role": user.Role})
+	audit.Annotate(c, audit.Event{
+		Action:     "auth.register",
+		EntityType: "user",
+		EntityID:   user.UserID.String(),
+		After:      gin.H{"username": user.Username, "email": user.Email, "role": role.RoleName},
+		ActorID:    &user.UserID,
+	})
+	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully", "role": role.RoleName})
 }
 
 // Login a user
@@ -80,22 +78,30 @@ func Login(c *gin.Context) {
 	// Fetch the user with the associated role
 	var user models.User
 	if err := config.DB.Preload("Role").Where("email = ?", input.Email).First(&user).Error; err != nil {
+		audit.Annotate(c, audit.Event{Action: "auth.login_failed", EntityType: "user", After: gin.H{"email": input.Email}})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
 	// Verify the password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
+		audit.Annotate(c, audit.Event{
+			Action:     "auth.login_failed",
+			EntityType: "user",
+			EntityID:   user.UserID.String(),
+			After:      gin.H{"email": input.Email},
+		})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.UserID.String(), user.Role.RoleName)
+	// Start a session and issue an access token with a refresh token
+	tokens, err := startSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	audit.Annotate(c, audit.Event{Action: "auth.login", EntityType: "user", EntityID: user.UserID.String(), ActorID: &user.UserID})
+	c.JSON(http.StatusOK, tokens)
 }
@@ -0,0 +1,432 @@
+package controllers
+
+import (
+	"errors"
+	"final/cache"
+	"final/config"
+	"final/inventory"
+	"final/models"
+	"final/wishlists"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errVariantInvalid is returned when a variant breaks a validation rule
+	errVariantInvalid = errors.New("invalid variant")
+	// errVariantTaken is returned when a variant's SKU or options are already in use
+	errVariantTaken = errors.New("variant already exists")
+	// errVariantNotFound is returned when the variant does not belong to the product
+	errVariantNotFound = errors.New("variant not found")
+	// errLastVariant is returned when deleting the only variant of a product
+	errLastVariant = errors.New("a product needs at least one variant")
+	// errVariantInStock is returned when deleting a variant that still has stock
+	errVariantInStock = errors.New("variant still has stock")
+	// errVariantHasHistory is returned when deleting a variant that stock reservations
+	// or the stock ledger refer to
+	errVariantHasHistory = errors.New("variant has stock history")
+)
+
+// orderVariants lists the default variant first, then the others in creation order
+func orderVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("is_default DESC, created_at, variant_id")
+}
+
+// optionMatrix lists the values each option takes across the variants, in the order
+// they first appear, e.g. {"size": ["S", "M"], "color": ["red"]}
+func optionMatrix(variants []models.ProductVariant) map[string][]string {
+	matrix := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, variant := range variants {
+		for name, value := range variant.Options {
+			text := fmt.Sprint(value)
+			if key := name + "\x00" + text; !seen[key] {
+				seen[key] = true
+				matrix[name] = append(matrix[name], text)
+			}
+		}
+	}
+	return matrix
+}
+
+// optionNames returns the sorted option names of a variant
+func optionNames(variant models.ProductVariant) []string {
+	names := make([]string, 0, len(variant.Options))
+	for name := range variant.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// optionKey identifies a variant's combination of option values
+func optionKey(variant models.ProductVariant) string {
+	names := optionNames(variant)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, variant.Options[name]))
+	}
+	return strings.Join(pairs, "\x00")
+}
+
+// validateVariant checks the fields of a variant
+func validateVariant(variant models.ProductVariant) error {
+	if variant.SKU == "" || len(variant.SKU) > 64 {
+		return fmt.Errorf("%w: SKU must be between 1 and 64 characters", errVariantInvalid)
+	}
+	for name, value := range variant.Options {
+		text, ok := value.(string)
+		if name == "" || len(name) > 50 || !ok || text == "" || len(text) > 100 {
+			return fmt.Errorf("%w: options must map names of up to 50 characters to values of up to 100", errVariantInvalid)
+		}
+	}
+	if variant.Price != nil && variant.Price.IsNegative() {
+		return fmt.Errorf("%w: price cannot be negative", errVariantInvalid)
+	}
+	if variant.WeightGrams != nil && *variant.WeightGrams < 0 {
+		return fmt.Errorf("%w: weight cannot be negative", errVariantInvalid)
+	}
+	if variant.Stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", errVariantInvalid)
+	}
+	return nil
+}
+
+// checkVariantSiblings verifies that a variant fits the product's other variants:
+// its SKU is unused, it takes the same option names and it is not a duplicate
+// combination. The product must be locked so concurrent changes see each other.
+func checkVariantSiblings(tx *gorm.DB, variant models.ProductVariant) error {
+	var count int64
+	if err := tx.Model(&models.ProductVariant{}).
+		Where("sku = ? AND variant_id <> ?", variant.SKU, variant.VariantID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: SKU %q is already in use", errVariantTaken, variant.SKU)
+	}
+
+	var siblings []models.ProductVariant
+	if err := tx.Where("product_id = ? AND variant_id <> ?", variant.ProductID, variant.VariantID).
+		Find(&siblings).Error; err != nil {
+		return err
+	}
+	names := strings.Join(optionNames(variant), ", ")
+	for _, sibling := range siblings {
+		if siblingNames := strings.Join(optionNames(sibling), ", "); siblingNames != names {
+			return fmt.Errorf("%w: every variant of the product must set the options [%s]", errVariantInvalid, siblingNames)
+		}
+		if optionKey(sibling) == optionKey(variant) {
+			return fmt.Errorf("%w: another variant has the same options", errVariantTaken)
+		}
+	}
+	return nil
+}
+
+// createVariant adds a variant to a locked product. Its stock is recorded in the
+// ledger as a restock, and a default variant takes over from the previous default.
+func createVariant(tx *gorm.DB, variant *models.ProductVariant, actorID uuid.UUID) error {
+	if err := checkVariantSiblings(tx, *variant); err != nil {
+		return err
+	}
+	if variant.IsDefault {
+		if err := clearDefaultVariant(tx, variant.ProductID); err != nil {
+			return err
+		}
+	}
+	if variant.Options == nil {
+		variant.Options = models.JSONMap{}
+	}
+
+	initialStock := variant.Stock
+	variant.Stock = 0
+	if err := tx.Create(variant).Error; err != nil {
+		return err
+	}
+	if initialStock == 0 {
+		return nil
+	}
+	movement := models.StockMovement{
+		ProductID: variant.ProductID,
+		VariantID: variant.VariantID,
+		Quantity:  initialStock,
+		Reason:    models.StockReasonRestock,
+		Note:      "Initial stock",
+		ActorID:   &actorID,
+	}
+	if err := inventory.Adjust(tx, &movement); err != nil {
+		return err
+	}
+	variant.Stock = movement.StockAfter
+	return nil
+}
+
+// clearDefaultVariant unmarks the product's default variant
+func clearDefaultVariant(tx *gorm.DB, productID uuid.UUID) error {
+	return tx.Model(&models.ProductVariant{}).
+		Where("product_id = ? AND is_default", productID).
+		Update("is_default", false).Error
+}
+
+// lockProduct loads a product with a row lock for the rest of the transaction
+func lockProduct(tx *gorm.DB, product *models.Product, productID interface{}) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(product, "product_id = ?", productID).Error
+}
+
+// resolveVariant finds the variant of a product that a request names, writing an
+// error response when there is none. Without a variant ID the product's only
+// variant is used; products with several variants need one to be chosen.
+func resolveVariant(c *gin.Context, product models.Product, variantID *uuid.UUID) (models.ProductVariant, bool) {
+	query := config.DB.Where("product_id = ?", product.ProductID)
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	}
+
+	var variants []models.ProductVariant
+	if err := query.Limit(2).Find(&variants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load variant"})
+		return models.ProductVariant{}, false
+	}
+	switch len(variants) {
+	case 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return models.ProductVariant{}, false
+	case 1:
+		return variants[0], true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "variant_id is required for products with several variants"})
+		return models.ProductVariant{}, false
+	}
+}
+
+// respondVariantError maps variant errors to HTTP responses
+func respondVariantError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, errVariantInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errVariantTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// variantInput is the payload for creating or updating a variant. Stock is only
+// set on creation; later changes go through inventory adjustments.
+type variantInput struct {
+	SKU         string            `json:"sku" binding:"required,max=64"`
+	Options     map[string]string `json:"options"`
+	Price       *models.Money     `json:"price"`
+	WeightGrams *int              `json:"weight_grams"`
+	Stock       int               `json:"stock"`
+	// IsDefault makes the variant the product's default; the default cannot be unset
+	// directly, only by making another variant the default
+	IsDefault bool `json:"is_default"`
+}
+
+// apply copies the input onto a variant
+func (input variantInput) apply(variant *models.ProductVariant) {
+	variant.SKU = strings.TrimSpace(input.SKU)
+	variant.Options = models.JSONMap{}
+	for name, value := range input.Options {
+		variant.Options[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	variant.Price = input.Price
+	variant.WeightGrams = input.WeightGrams
+	if input.IsDefault {
+		variant.IsDefault = true
+	}
+}
+
+// Get a product's variants together with the values each option takes
+func GetProductVariants(c *gin.Context) {
+	var product models.Product
+	if err := config.DB.Preload("Variants", orderVariants).First(&product, "product_id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"product_id": product.ProductID,
+		"options":    optionMatrix(product.Variants),
+		"variants":   product.Variants,
+	})
+}
+
+// Add a variant to a product, with its initial stock
+func CreateProductVariant(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input variantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var variant models.ProductVariant
+	input.apply(&variant)
+	variant.Stock = input.Stock
+	if err := validateVariant(variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := lockProduct(tx, &product, c.Param("id")); err != nil {
+			return err
+		}
+		variant.ProductID = product.ProductID
+		return createVariant(tx, &variant, actorID)
+	})
+	if err != nil {
+		respondVariantError(c, err, "Failed to create variant")
+		return
+	}
+
+	invalidateProductCache(c, cache.ProductsTag, productCacheTag(variant.ProductID)) // Lists embed variants
+	recordAudit(c, "product_variant.create", "product_variant", variant.VariantID, nil, variant)
+	c.JSON(http.StatusCreated, variant)
+}
+
+// Update a variant's SKU, options, price or weight
+func UpdateProductVariant(c *gin.Context) {
+	var input variantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var before, variant models.ProductVariant
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := lockProduct(tx, &product, c.Param("id")); err != nil {
+			return err
+		}
+		if err := tx.First(&variant, "variant_id = ? AND product_id = ?", c.Param("variant_id"), product.ProductID).Error; err != nil {
+			return errVariantNotFound
+		}
+
+		before = variant
+		input.apply(&variant)
+		if err := validateVariant(variant); err != nil {
+			return err
+		}
+		if err := checkVariantSiblings(tx, variant); err != nil {
+			return err
+		}
+		if variant.IsDefault && !before.IsDefault {
+			if err := clearDefaultVariant(tx, product.ProductID); err != nil {
+				return err
+			}
+		}
+		// Price and weight may be cleared to fall back to the product's, so save every column
+		if err := tx.Model(&variant).Select("sku", "options", "price", "weight_grams", "is_default").Updates(&variant).Error; err != nil {
+			return err
+		}
+		return wishlists.NotifyPriceDrop(tx, product, &variant, before.EffectivePrice(product), variant.EffectivePrice(product))
+	})
+	if errors.Is(err, errVariantNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return
+	}
+	if err != nil {
+		respondVariantError(c, err, "Failed to update variant")
+		return
+	}
+
+	invalidateProductCache(c, cache.ProductsTag, productCacheTag(variant.ProductID)) // Lists embed variants
+	recordAudit(c, "product_variant.update", "product_variant", variant.VariantID, before, variant)
+	c.JSON(http.StatusOK, variant)
+}
+
+// variantHasHistory reports whether stock reservations or stock movements refer to the variant
+func variantHasHistory(tx *gorm.DB, variantID uuid.UUID) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.StockReservation{}).Where("variant_id = ?", variantID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	err := tx.Model(&models.StockMovement{}).Where("variant_id = ?", variantID).Count(&count).Error
+	return count > 0, err
+}
+
+// Delete a variant that never held stock. The product keeps at least one variant; when the
+// default is deleted the oldest remaining variant becomes the default.
+func DeleteProductVariant(c *gin.Context) {
+	var variant models.ProductVariant
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		if err := lockProduct(tx, &product, c.Param("id")); err != nil {
+			return err
+		}
+		if err := tx.First(&variant, "variant_id = ? AND product_id = ?", c.Param("variant_id"), product.ProductID).Error; err != nil {
+			return errVariantNotFound
+		}
+		if variant.Stock != 0 {
+			return errVariantInStock
+		}
+		// Reserved units have already left Stock, and the ledger must keep its history
+		hasHistory, err := variantHasHistory(tx, variant.VariantID)
+		if err != nil {
+			return err
+		}
+		if hasHistory {
+			return errVariantHasHistory
+		}
+
+		var remaining []models.ProductVariant
+		if err := orderVariants(tx).Where("product_id = ? AND variant_id <> ?", product.ProductID, variant.VariantID).
+			Find(&remaining).Error; err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			return errLastVariant
+		}
+
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		if variant.IsDefault {
+			return tx.Model(&remaining[0]).Update("is_default", true).Error
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	case errors.Is(err, errVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return
+	case errors.Is(err, errVariantInStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "Adjust the variant's stock to zero before deleting it"})
+		return
+	case errors.Is(err, errVariantHasHistory):
+		c.JSON(http.StatusConflict, gin.H{"error": "The variant has stock reservations or stock history and cannot be deleted"})
+		return
+	case errors.Is(err, errLastVariant):
+		c.JSON(http.StatusConflict, gin.H{"error": "A product needs at least one variant"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete variant"})
+		return
+	}
+
+	invalidateProductCache(c, cache.ProductsTag, productCacheTag(variant.ProductID)) // Lists embed variants
+	recordAudit(c, "product_variant.delete", "product_variant", variant.VariantID, variant, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted"})
+}
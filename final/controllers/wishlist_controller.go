@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"errors"
+	"final/config"
+	"final/models"
+	"final/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errWishlistItemNotFound is returned when a wishlist item does not belong to the user
+var errWishlistItemNotFound = errors.New("wishlist item not found")
+
+// wishlistInput is the payload for creating or renaming a wishlist
+type wishlistInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// sharedWishlist is the public view of a shared wishlist, without its owner
+type sharedWishlist struct {
+	Name      string
+	Items     []models.WishlistItem
+	CreatedAt time.Time
+}
+
+// preloadWishlistItems loads a wishlist's items with their products and variants, oldest first
+func preloadWishlistItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, wishlist_item_id")
+	}).Preload("Items.Product").Preload("Items.Variant")
+}
+
+// wishlistNameTaken reports whether the user has another wishlist with the name
+func wishlistNameTaken(db *gorm.DB, userID uuid.UUID, name string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Wishlist{}).
+		Where("user_id = ? AND name = ? AND wishlist_id <> ?", userID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// findWishlist loads one of the user's wishlists, writing a 404 response when there is none
+func findWishlist(c *gin.Context, userID uuid.UUID, wishlist *models.Wishlist) bool {
+	if err := config.DB.Where("wishlist_id = ? AND user_id = ?", c.Param("id"), userID).
+		First(wishlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return false
+	}
+	return true
+}
+
+// respondWithWishlist writes a wishlist with its items
+func respondWithWishlist(c *gin.Context, wishlistID uuid.UUID, status int) {
+	var wishlist models.Wishlist
+	if err := preloadWishlistItems(config.DB).First(&wishlist, "wishlist_id = ?", wishlistID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load wishlist"})
+		return
+	}
+	c.JSON(status, wishlist)
+}
+
+// saveWishlistName validates the input and renames the wishlist
+func saveWishlistName(c *gin.Context, wishlist *models.Wishlist) bool {
+	var input wishlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	wishlist.Name = strings.TrimSpace(input.Name)
+	if wishlist.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be blank"})
+		return false
+	}
+
+	taken, err := wishlistNameTaken(config.DB, wishlist.UserID, wishlist.Name, wishlist.WishlistID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check wishlist name"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a wishlist with this name"})
+		return false
+	}
+
+	if err := config.DB.Save(wishlist).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save wishlist"})
+		return false
+	}
+	return true
+}
+
+// List the current user's wishlists with their items
+func GetWishlists(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlists []models.Wishlist
+	if err := preloadWishlistItems(config.DB).
+		Where("user_id = ?", userID).
+		Order("created_at, wishlist_id").
+		Find(&wishlists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wishlists"})
+		return
+	}
+	c.JSON(http.StatusOK, wishlists)
+}
+
+// Create a named wishlist
+func CreateWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	wishlist := models.Wishlist{UserID: userID}
+	if !saveWishlistName(c, &wishlist) {
+		return
+	}
+	c.JSON(http.StatusCreated, wishlist)
+}
+
+// Get one of the current user's wishlists
+func GetWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+	respondWithWishlist(c, wishlist.WishlistID, http.StatusOK)
+}
+
+// Rename a wishlist
+func UpdateWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+	if !saveWishlistName(c, &wishlist) {
+		return
+	}
+	respondWithWishlist(c, wishlist.WishlistID, http.StatusOK)
+}
+
+// Delete a wishlist and its items
+func DeleteWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("wishlist_id = ?", wishlist.WishlistID).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&wishlist).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete wishlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist deleted"})
+}
+
+// Add a product to a wishlist, optionally pinned to one variant. Adding a product
+// that is already on the list leaves the list unchanged.
+func AddWishlistItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ProductID uuid.UUID  `json:"product_id" binding:"required"`
+		VariantID *uuid.UUID `json:"variant_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, "product_id = ?", input.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	if input.VariantID != nil {
+		// Only an explicitly chosen variant is pinned
+		if _, ok := resolveVariant(c, product, input.VariantID); !ok {
+			return
+		}
+	}
+
+	// idx_wishlist_items_entry turns adding an item that is already on the list,
+	// including by a concurrent request, into a no-op
+	item := models.WishlistItem{WishlistID: wishlist.WishlistID, ProductID: product.ProductID, VariantID: input.VariantID}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&item)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to wishlist"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondWithWishlist(c, wishlist.WishlistID, http.StatusOK)
+		return
+	}
+	respondWithWishlist(c, wishlist.WishlistID, http.StatusCreated)
+}
+
+// Remove an item from a wishlist
+func RemoveWishlistItem(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+
+	result := config.DB.Where("wishlist_item_id = ? AND wishlist_id = ?", c.Param("item_id"), wishlist.WishlistID).
+		Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove wishlist item"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return
+	}
+	respondWithWishlist(c, wishlist.WishlistID, http.StatusOK)
+}
+
+// Move a wishlist item into the cart. An item not pinned to a variant needs one to
+// be chosen, unless its product has only one; quantity defaults to 1.
+func MoveWishlistItemToCart(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		VariantID *uuid.UUID `json:"variant_id"`
+		Quantity  int        `json:"quantity" binding:"omitempty,min=1"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+	var item models.WishlistItem
+	if err := config.DB.Preload("Product").
+		Where("wishlist_item_id = ? AND wishlist_id = ?", c.Param("item_id"), wishlist.WishlistID).
+		First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return
+	}
+
+	variantID := item.VariantID
+	if variantID == nil {
+		variantID = input.VariantID
+	}
+	variant, ok := resolveVariant(c, item.Product, variantID)
+	if !ok {
+		return
+	}
+
+	cart, err := getOrCreateCart(config.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cart"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := addToCart(tx, cart, item.Product, variant, input.Quantity); err != nil {
+			return err
+		}
+		result := tx.Delete(&models.WishlistItem{}, "wishlist_item_id = ?", item.WishlistItemID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// A concurrent request already moved or removed the item
+			return errWishlistItemNotFound
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errWishlistItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move item to cart"})
+		return
+	}
+
+	respondWithCart(c, userID, http.StatusOK)
+}
+
+// Share a wishlist through a public link. Sharing again returns the same link.
+func ShareWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+
+	if wishlist.ShareToken == nil {
+		token, err := utils.GenerateShareToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share wishlist"})
+			return
+		}
+		if err := config.DB.Model(&wishlist).Update("share_token", token).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share wishlist"})
+			return
+		}
+		wishlist.ShareToken = &token
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"share_token": *wishlist.ShareToken,
+		"share_path":  "/wishlists/shared/" + *wishlist.ShareToken,
+	})
+}
+
+// Stop sharing a wishlist; its link stops working and sharing again creates a new one
+func UnshareWishlist(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var wishlist models.Wishlist
+	if !findWishlist(c, userID, &wishlist) {
+		return
+	}
+	if err := config.DB.Model(&wishlist).Update("share_token", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop sharing wishlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist is no longer shared"})
+}
+
+// View a shared wishlist by its link; no login is needed
+func GetSharedWishlist(c *gin.Context) {
+	var wishlist models.Wishlist
+	if err := preloadWishlistItems(config.DB).
+		Where("share_token = ?", c.Param("token")).
+		First(&wishlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+	c.JSON(http.StatusOK, sharedWishlist{Name: wishlist.Name, Items: wishlist.Items, CreatedAt: wishlist.CreatedAt})
+}
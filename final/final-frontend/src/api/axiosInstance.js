@@ -15,4 +15,56 @@ API.interceptors.request.use((config) => {
   return config;
 });
 
+// Exchange the refresh token for a new access token; access tokens expire after
+// 15 minutes. The server rotates the refresh token on every use, so store both.
+const refreshTokens = async () => {
+  const refreshToken = localStorage.getItem("refresh_token");
+  if (!refreshToken) {
+    throw new Error("No refresh token");
+  }
+  // Plain axios, so a rejected refresh does not trigger another refresh
+  const response = await axios.post(
+    `${API.defaults.baseURL}/users/refresh`,
+    { refresh_token: refreshToken },
+    { withCredentials: true }
+  );
+  localStorage.setItem("token", response.data.token);
+  localStorage.setItem("refresh_token", response.data.refresh_token);
+  return response.data.token;
+};
+
+// Requests failing together share one refresh: reusing a rotated refresh token
+// revokes the session
+let refreshing = null;
+
+// Refresh the tokens when a request is rejected with 401 and retry it once
+API.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const request = error.config;
+    const isAuthRequest = ["/users/login", "/users/refresh"].includes(request?.url);
+    if (error.response?.status !== 401 || !request || request._retried || isAuthRequest) {
+      return Promise.reject(error);
+    }
+    request._retried = true;
+
+    try {
+      if (!refreshing) {
+        refreshing = refreshTokens().finally(() => {
+          refreshing = null;
+        });
+      }
+      const token = await refreshing;
+      request.headers.Authorization = `Bearer ${token}`;
+      return API(request);
+    } catch (refreshError) {
+      // The session is over; log in again
+      localStorage.removeItem("token");
+      localStorage.removeItem("refresh_token");
+      window.location.assign("/login");
+      return Promise.reject(error);
+    }
+  }
+);
+
 export default API;
@@ -8,7 +8,7 @@ const ProductList = () => {
     const fetchProducts = async () => {
       try {
         const response = await API.get("/products/");
-        setProducts(response.data);
+        setProducts(response.data.data);
       } catch (err) {
         console.error("Failed to fetch products:", err);
       }
@@ -18,7 +18,7 @@ const AdminPanel = () => {
     const fetchProducts = async () => {
       try {
         const response = await API.get("/products/");
-        setProducts(response.data);
+        setProducts(response.data.data);
       } catch (err) {
         setError("Failed to fetch products");
       }
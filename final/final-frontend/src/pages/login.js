@@ -13,6 +13,7 @@ const Login = () => {
     try {
       const response = await API.post("/users/login", { email, password });
       localStorage.setItem("token", response.data.token); // Store token
+      localStorage.setItem("refresh_token", response.data.refresh_token); // Store refresh token
       navigate("/dashboard"); // Redirect to dashboard
     } catch (err) {
       setError(err.response?.data?.error || "Login failed");
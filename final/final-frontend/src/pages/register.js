@@ -5,7 +5,6 @@ const Register = () => {
   const [username, setUsername] = useState("");
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
-  const [role, setRole] = useState("user");
   const [message, setMessage] = useState(null);
 
   const handleRegister = async (e) => {
@@ -15,7 +14,6 @@ const Register = () => {
         username,
         email,
         password,
-        role,
       });
       setMessage(response.data.message);
     } catch (err) {
@@ -48,10 +46,6 @@ const Register = () => {
           onChange={(e) => setPassword(e.target.value)}
           required
         />
-        <select value={role} onChange={(e) => setRole(e.target.value)}>
-          <option value="user">User</option>
-          <option value="admin">Admin</option>
-        </select>
         <button type="submit">Register</button>
       </form>
       {message && <p>{message}</p>}
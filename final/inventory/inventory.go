@@ -0,0 +1,116 @@
+// Package inventory changes the stock of product variants. Every change goes through
+// Adjust, which records it in the stock movement ledger and raises low-stock notifications.
+package inventory
+
+import (
+	"errors"
+	"final/models"
+	"final/notifications"
+	"final/wishlists"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientStock is returned when a change would take stock below zero
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// LowStockThreshold is the stock level at or below which a product counts as low
+// on stock, unless the product sets its own threshold; from LOW_STOCK_THRESHOLD
+var LowStockThreshold = 5
+
+// ReservationTTL is how long stock stays reserved for a checkout, from RESERVATION_TTL
+var ReservationTTL = 15 * time.Minute
+
+// Init reads the inventory settings from the environment
+func Init() error {
+	if raw := os.Getenv("LOW_STOCK_THRESHOLD"); raw != "" {
+		threshold, err := strconv.Atoi(raw)
+		if err != nil || threshold < 0 {
+			return fmt.Errorf("invalid LOW_STOCK_THRESHOLD %q", raw)
+		}
+		LowStockThreshold = threshold
+	}
+	if raw := os.Getenv("RESERVATION_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid RESERVATION_TTL %q", raw)
+		}
+		ReservationTTL = ttl
+	}
+	return nil
+}
+
+// Threshold returns the low-stock threshold that applies to a product
+func Threshold(product models.Product) int {
+	if product.LowStockThreshold != nil {
+		return *product.LowStockThreshold
+	}
+	return LowStockThreshold
+}
+
+// Adjust applies movement.Quantity to the variant's stock, keeps the product's total
+// in step and records the movement. The product row is locked before the variant
+// row, for the rest of the transaction, so callers changing several variants must
+// go through them ordered by product. StockAfter and the movement's ID are filled
+// in. Users who manage inventory are notified when the change takes the variant
+// down to its product's low-stock threshold, and users who wish for the variant
+// when it comes back in stock.
+func Adjust(tx *gorm.DB, movement *models.StockMovement) error {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&product, "product_id = ?", movement.ProductID).Error; err != nil {
+		return err
+	}
+	var variant models.ProductVariant
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&variant, "variant_id = ? AND product_id = ?", movement.VariantID, movement.ProductID).Error; err != nil {
+		return err
+	}
+
+	stock := variant.Stock + movement.Quantity
+	if stock < 0 {
+		return fmt.Errorf("%w: only %d of %q available", ErrInsufficientStock, variant.Stock, variant.Label(product))
+	}
+	// Update writes the new stock back into the structs, so keep the old levels
+	previous, productPrevious := variant.Stock, product.Stock
+	if err := tx.Model(&variant).Update("stock", stock).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&product).Update("stock", product.Stock+movement.Quantity).Error; err != nil {
+		return err
+	}
+
+	movement.StockAfter = stock
+	if err := tx.Create(movement).Error; err != nil {
+		return err
+	}
+
+	// Stock returning from a released reservation was only held, never sold out
+	if previous <= 0 && stock > 0 && movement.Reason != models.StockReasonRelease {
+		if err := wishlists.NotifyBackInStock(tx, product, variant, productPrevious); err != nil {
+			return err
+		}
+	}
+
+	threshold := Threshold(product)
+	if previous > threshold && stock <= threshold {
+		return notifications.NotifyPermission(tx, models.PermissionInventoryManage, notifications.Message{
+			Type:    models.NotificationLowStock,
+			Title:   fmt.Sprintf("%s is low on stock", variant.Label(product)),
+			Message: fmt.Sprintf("%d left, threshold is %d", stock, threshold),
+			Data: models.JSONMap{
+				"product_id": product.ProductID.String(),
+				"variant_id": variant.VariantID.String(),
+				"sku":        variant.SKU,
+				"stock":      stock,
+				"threshold":  threshold,
+			},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package inventory_test
+
+import (
+	"errors"
+	"final/inventory"
+	"final/models"
+	"final/testdb"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// adjust applies a stock change to the variant and fails the test on error
+func adjust(t *testing.T, tx *gorm.DB, variant models.ProductVariant, quantity int, reason string) {
+	t.Helper()
+	if err := inventory.Adjust(tx, &models.StockMovement{
+		ProductID: variant.ProductID,
+		VariantID: variant.VariantID,
+		Quantity:  quantity,
+		Reason:    reason,
+	}); err != nil {
+		t.Fatalf("Adjust(%d) failed: %v", quantity, err)
+	}
+}
+
+// countNotifications counts the user's notifications of a type about a product
+func countNotifications(t *testing.T, tx *gorm.DB, userID uuid.UUID, kind string, productID uuid.UUID) int64 {
+	t.Helper()
+	var count int64
+	if err := tx.Model(&models.Notification{}).
+		Where("user_id = ? AND type = ? AND data->>'product_id' = ?", userID, kind, productID.String()).
+		Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count notifications: %v", err)
+	}
+	return count
+}
+
+func TestAdjustAlertsOnceWhenCrossingLowStockThreshold(t *testing.T) {
+	tx := testdb.Open(t)
+	admin := testdb.User(t, tx, "admin")
+	product, variant := testdb.Product(t, tx, 10)
+	if err := tx.Model(&product).Update("low_stock_threshold", 5).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	adjust(t, tx, variant, -4, models.StockReasonDamage)
+	if got := countNotifications(t, tx, admin.UserID, models.NotificationLowStock, product.ProductID); got != 0 {
+		t.Fatalf("alerts above the threshold = %d, want 0", got)
+	}
+
+	adjust(t, tx, variant, -1, models.StockReasonDamage)
+	if got := countNotifications(t, tx, admin.UserID, models.NotificationLowStock, product.ProductID); got != 1 {
+		t.Fatalf("alerts after reaching the threshold = %d, want 1", got)
+	}
+
+	adjust(t, tx, variant, -1, models.StockReasonDamage)
+	if got := countNotifications(t, tx, admin.UserID, models.NotificationLowStock, product.ProductID); got != 1 {
+		t.Fatalf("alerts below the threshold = %d, want still 1", got)
+	}
+}
+
+func TestAdjustKeepsProductTotalAndLedger(t *testing.T) {
+	tx := testdb.Open(t)
+	product, variant := testdb.Product(t, tx, 3)
+
+	movement := models.StockMovement{
+		ProductID: variant.ProductID,
+		VariantID: variant.VariantID,
+		Quantity:  4,
+		Reason:    models.StockReasonRestock,
+	}
+	if err := inventory.Adjust(tx, &movement); err != nil {
+		t.Fatal(err)
+	}
+	if movement.StockAfter != 7 {
+		t.Errorf("StockAfter = %d, want 7", movement.StockAfter)
+	}
+	if err := tx.First(&product, "product_id = ?", product.ProductID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if product.Stock != 7 {
+		t.Errorf("product stock = %d, want 7", product.Stock)
+	}
+
+	err := inventory.Adjust(tx, &models.StockMovement{
+		ProductID: variant.ProductID,
+		VariantID: variant.VariantID,
+		Quantity:  -8,
+		Reason:    models.StockReasonDamage,
+	})
+	if !errors.Is(err, inventory.ErrInsufficientStock) {
+		t.Fatalf("taking more than the stock: err = %v, want ErrInsufficientStock", err)
+	}
+}
+
+// wishFor puts the product on a new wishlist of a new user and returns the user
+func wishFor(t *testing.T, tx *gorm.DB, productID uuid.UUID) models.User {
+	t.Helper()
+	user := testdb.User(t, tx, "user")
+	wishlist := models.Wishlist{UserID: user.UserID, Name: "Wishlist"}
+	if err := tx.Create(&wishlist).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(&models.WishlistItem{WishlistID: wishlist.WishlistID, ProductID: productID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestAdjustNotifiesWishersWhenBackInStock(t *testing.T) {
+	tx := testdb.Open(t)
+	product, variant := testdb.Product(t, tx, 0)
+	wisher := wishFor(t, tx, product.ProductID)
+
+	adjust(t, tx, variant, 3, models.StockReasonRestock)
+	if got := countNotifications(t, tx, wisher.UserID, models.NotificationBackInStock, product.ProductID); got != 1 {
+		t.Fatalf("back-in-stock notifications after restock = %d, want 1", got)
+	}
+
+	// Restocking what is still in stock is not news
+	adjust(t, tx, variant, 2, models.StockReasonRestock)
+	if got := countNotifications(t, tx, wisher.UserID, models.NotificationBackInStock, product.ProductID); got != 1 {
+		t.Fatalf("back-in-stock notifications after second restock = %d, want still 1", got)
+	}
+}
+
+func TestAdjustDoesNotNotifyWishersOnReservationRelease(t *testing.T) {
+	tx := testdb.Open(t)
+	product, variant := testdb.Product(t, tx, 0)
+	wisher := wishFor(t, tx, product.ProductID)
+
+	adjust(t, tx, variant, 2, models.StockReasonRelease)
+	if got := countNotifications(t, tx, wisher.UserID, models.NotificationBackInStock, product.ProductID); got != 0 {
+		t.Fatalf("back-in-stock notifications after a release = %d, want 0", got)
+	}
+}
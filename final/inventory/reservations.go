@@ -0,0 +1,195 @@
+package inventory
+
+import (
+	"context"
+	"final/cache"
+	"final/models"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Item is a quantity of one product variant
+type Item struct {
+	ProductID uuid.UUID
+	VariantID uuid.UUID
+	Quantity  int
+}
+
+// SortItems orders items by product and then variant, the order in which Adjust
+// must lock them to avoid deadlocks
+func SortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].ProductID != items[j].ProductID {
+			return items[i].ProductID.String() < items[j].ProductID.String()
+		}
+		return items[i].VariantID.String() < items[j].VariantID.String()
+	})
+}
+
+// Reserve holds stock for the user's checkout, replacing any reservations the user
+// already has. Items should name each variant once. The reserved quantities leave
+// the variants' stock until they are released by checkout, by Release or when
+// they expire.
+func Reserve(tx *gorm.DB, userID uuid.UUID, items []Item) ([]models.StockReservation, error) {
+	previous, err := LockReservations(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	productIDs := make([]uuid.UUID, 0, len(previous)+len(items))
+	for _, reservation := range previous {
+		productIDs = append(productIDs, reservation.ProductID)
+	}
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	if _, err := LockProducts(tx, productIDs); err != nil {
+		return nil, err
+	}
+	if err := ReleaseReservations(tx, previous); err != nil {
+		return nil, err
+	}
+
+	SortItems(items)
+	expiresAt := time.Now().Add(ReservationTTL)
+	reservations := make([]models.StockReservation, 0, len(items))
+	for _, item := range items {
+		if err := Adjust(tx, &models.StockMovement{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  -item.Quantity,
+			Reason:    models.StockReasonReservation,
+			ActorID:   &userID,
+		}); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, models.StockReservation{
+			UserID:    userID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			ExpiresAt: expiresAt,
+		})
+	}
+	if len(reservations) == 0 {
+		return reservations, nil
+	}
+	if err := tx.Create(&reservations).Error; err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// Release returns the user's reserved stock
+func Release(tx *gorm.DB, userID uuid.UUID) error {
+	reservations, err := LockReservations(tx, userID)
+	if err != nil {
+		return err
+	}
+	return ReleaseReservations(tx, reservations)
+}
+
+// LockReservations locks the user's reservations for the rest of the transaction.
+// Reservations are locked before the products they hold stock of, as ReleaseExpired
+// does.
+func LockReservations(tx *gorm.DB, userID uuid.UUID) ([]models.StockReservation, error) {
+	var reservations []models.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		Order("product_id, variant_id").
+		Find(&reservations).Error
+	return reservations, err
+}
+
+// ReleaseReservations returns the stock of reservations locked by LockReservations
+// and deletes them
+func ReleaseReservations(tx *gorm.DB, reservations []models.StockReservation) error {
+	return release(tx, reservations, "")
+}
+
+// LockProducts locks the products in product order for the rest of the transaction.
+// A transaction that releases reservations and then takes stock of other products
+// locks all of them here first; locking them in two passes could take two products
+// in opposite orders in concurrent transactions and deadlock.
+func LockProducts(tx *gorm.DB, productIDs []uuid.UUID) ([]models.Product, error) {
+	var products []models.Product
+	if len(productIDs) == 0 {
+		return products, nil
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id IN ?", productIDs).
+		Order("product_id").
+		Find(&products).Error
+	return products, err
+}
+
+// ReleaseExpired returns the stock of expired reservations and reports how many
+// were released. Reservations locked by a checkout in progress are skipped.
+func ReleaseExpired(db *gorm.DB) (int, error) {
+	var reservations []models.StockReservation
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("expires_at <= ?", time.Now()).
+			Order("product_id, variant_id").
+			Find(&reservations).Error; err != nil {
+			return err
+		}
+		return release(tx, reservations, "Reservation expired")
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(reservations), nil
+}
+
+// release returns the stock of locked reservations and deletes them
+func release(tx *gorm.DB, reservations []models.StockReservation, note string) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(reservations))
+	for _, reservation := range reservations {
+		userID := reservation.UserID
+		if err := Adjust(tx, &models.StockMovement{
+			ProductID: reservation.ProductID,
+			VariantID: reservation.VariantID,
+			Quantity:  reservation.Quantity,
+			Reason:    models.StockReasonRelease,
+			Note:      note,
+			ActorID:   &userID,
+		}); err != nil {
+			return err
+		}
+		ids = append(ids, reservation.ReservationID)
+	}
+	return tx.Delete(&models.StockReservation{}, "reservation_id IN ?", ids).Error
+}
+
+// StartReleaser releases expired reservations every interval
+func StartReleaser(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			released, err := ReleaseExpired(db)
+			if err != nil {
+				log.Printf("Failed to release expired stock reservations: %v", err)
+				continue
+			}
+			if released == 0 {
+				continue
+			}
+			log.Printf("Released %d expired stock reservations", released)
+			// Cached product responses show the old stock
+			if cache.Default != nil {
+				if err := cache.Default.InvalidateTags(context.Background(), cache.ProductsTag); err != nil {
+					log.Printf("Cache invalidation of %s failed: %v", cache.ProductsTag, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,60 @@
+package inventory_test
+
+import (
+	"final/inventory"
+	"final/models"
+	"final/testdb"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// variantStock reads a variant's current stock
+func variantStock(t *testing.T, tx *gorm.DB, variantID uuid.UUID) int {
+	t.Helper()
+	var variant models.ProductVariant
+	if err := tx.First(&variant, "variant_id = ?", variantID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return variant.Stock
+}
+
+func TestReserveReplacesPreviousReservation(t *testing.T) {
+	tx := testdb.Open(t)
+	user := testdb.User(t, tx, "user")
+	_, first := testdb.Product(t, tx, 5)
+	_, second := testdb.Product(t, tx, 5)
+
+	if _, err := inventory.Reserve(tx, user.UserID, []inventory.Item{
+		{ProductID: first.ProductID, VariantID: first.VariantID, Quantity: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := inventory.Reserve(tx, user.UserID, []inventory.Item{
+		{ProductID: second.ProductID, VariantID: second.VariantID, Quantity: 3},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := variantStock(t, tx, first.VariantID); got != 5 {
+		t.Errorf("stock of the replaced reservation = %d, want 5", got)
+	}
+	if got := variantStock(t, tx, second.VariantID); got != 2 {
+		t.Errorf("stock of the new reservation = %d, want 2", got)
+	}
+
+	if err := inventory.Release(tx, user.UserID); err != nil {
+		t.Fatal(err)
+	}
+	if got := variantStock(t, tx, second.VariantID); got != 5 {
+		t.Errorf("stock after release = %d, want 5", got)
+	}
+	var count int64
+	if err := tx.Model(&models.StockReservation{}).Where("user_id = ?", user.UserID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("reservations after release = %d, want 0", count)
+	}
+}
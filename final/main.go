@@ -1,35 +1,107 @@
 package main
 
 import (
+	"final/audit"
+	"final/cache"
 	"final/config"
+	"final/inventory"
+	"final/middlewares"
 	"final/migrations"
+	"final/models"
+	"final/pricing"
 	"final/routes"
+	"final/storage"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/cors"
 )
 
 func main() {
+	// "migrate" runs schema migrations instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.RunCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Amounts are stored and served in the store currency
+	if err := models.InitCurrency(); err != nil {
+		log.Fatalf("Failed to configure currency: %v", err)
+	}
+
 	// Connect to the database
 	config.ConnectDatabase()
 
-	// Run migrations
+	// Load the access token signing keys
+	if err := config.LoadJWTConfig(); err != nil {
+		log.Fatalf("Failed to load JWT configuration: %v", err)
+	}
+
+	// Refuse to start on an outdated schema, then seed environment-specific data
 	migrations.RunMigrations()
 
+	// Configure file storage for uploads
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Failed to configure storage: %v", err)
+	}
+
+	// Configure the response cache and purge its expired entries in the background
+	if err := cache.Init(); err != nil {
+		log.Fatalf("Failed to configure cache: %v", err)
+	}
+	cache.StartPurger(10 * time.Minute)
+
+	// Load the inventory settings and return expired stock reservations in the background
+	if err := inventory.Init(); err != nil {
+		log.Fatalf("Failed to configure inventory: %v", err)
+	}
+	inventory.StartReleaser(config.DB, time.Minute)
+
+	// Select the tax and shipping calculators used for quotes and checkout
+	if err := pricing.Init(); err != nil {
+		log.Fatalf("Failed to configure pricing: %v", err)
+	}
+
+	// Write audit log entries in the background
+	audit.Start(config.DB)
+
 	// Initialize Gin router
 	router := gin.Default()
+	router.Use(middlewares.RequestIDMiddleware(), middlewares.AuditMiddleware())
+
+	// Serve uploaded files when they are stored on the local filesystem
+	if local, ok := storage.Default.(*storage.LocalStorage); ok {
+		router.Static("/uploads", local.Dir)
+	}
 
 	// Register routes
 	routes.RegisterUserRoutes(router)
+	routes.RegisterWellKnownRoutes(router)
+	routes.RegisterRoleRoutes(router)
+	routes.RegisterAuditRoutes(router)
+	routes.RegisterReportRoutes(router)
+	routes.RegisterInventoryRoutes(router)
+	routes.RegisterNotificationRoutes(router)
+	routes.RegisterCouponRoutes(router)
+	routes.RegisterPricingRoutes(router)
 	routes.RegisterProductRoutes(router)
+	routes.RegisterCategoryRoutes(router)
+	routes.RegisterAddressRoutes(router)
+	routes.RegisterCartRoutes(router)
+	routes.RegisterWishlistRoutes(router)
+	routes.RegisterOrderRoutes(router)
+	routes.RegisterReviewRoutes(router)
 
 	// CORS Middleware
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},        // Frontend URL
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"}, // HTTP methods
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key"},
 		AllowCredentials: true,
 	})
 
@@ -1,9 +1,13 @@
 package middlewares
 
 import (
+	"final/config"
+	"final/models"
 	"final/utils"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -35,9 +39,30 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Store claims (user_id and role) in the context
+		// Reject tokens whose session was revoked or has expired
+		var session models.Session
+		if err := config.DB.Select("session_id", "revoked_at", "expires_at").
+			Where("session_id = ? AND user_id = ?", fmt.Sprint(claims["sid"]), fmt.Sprint(claims["user_id"])).
+			First(&session).Error; err != nil || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked or expired"})
+			c.Abort()
+			return
+		}
+
+		// Look up what the role may do; the lookup is cached so this rarely hits the database
+		role := fmt.Sprint(claims["role"])
+		permissions, err := rolePermissions(role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+			c.Abort()
+			return
+		}
+
+		// Store claims (user_id, role and session) and the role's permissions in the context
 		c.Set("user_id", claims["user_id"])
-		c.Set("role", claims["role"])
+		c.Set("role", role)
+		c.Set("session_id", session.SessionID.String())
+		c.Set("permissions", permissions)
 		c.Next()
 	}
 }
@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"final/config"
+	"final/models"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionCacheTTL bounds how long another instance may keep using a role's old
+// permissions; changes made through this instance invalidate the cache immediately
+const permissionCacheTTL = time.Minute
+
+// cachedPermissions is the permission set of one role
+type cachedPermissions struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+var permissionCache = struct {
+	sync.RWMutex
+	roles map[string]cachedPermissions
+}{roles: make(map[string]cachedPermissions)}
+
+// rolePermissions returns the permissions granted to a role, loading them from the
+// database when they are not cached or the cached copy is too old
+func rolePermissions(roleName string) (map[string]bool, error) {
+	permissionCache.RLock()
+	cached, ok := permissionCache.roles[roleName]
+	permissionCache.RUnlock()
+	if ok && time.Since(cached.loadedAt) < permissionCacheTTL {
+		return cached.permissions, nil
+	}
+
+	var names []string
+	if err := config.DB.Model(&models.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.permission_id").
+		Joins("JOIN roles ON roles.role_id = role_permissions.role_id").
+		Where("roles.role_name = ?", roleName).
+		Pluck("permissions.name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	permissionCache.Lock()
+	permissionCache.roles[roleName] = cachedPermissions{permissions: permissions, loadedAt: time.Now()}
+	permissionCache.Unlock()
+	return permissions, nil
+}
+
+// InvalidatePermissions drops the cached permissions so the next request reloads them
+func InvalidatePermissions() {
+	permissionCache.Lock()
+	permissionCache.roles = make(map[string]cachedPermissions)
+	permissionCache.Unlock()
+}
+
+// HasPermission reports whether the authenticated user's role grants a permission
+func HasPermission(c *gin.Context, permission string) bool {
+	permissions, _ := c.Get("permissions")
+	granted, _ := permissions.(map[string]bool)
+	return granted[permission]
+}
+
+// RequirePermission ensures the user's role grants the permission needed for the endpoint
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Permissions are set by AuthMiddleware
+		if _, exists := c.Get("permissions"); !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access forbidden: no role found"})
+			c.Abort()
+			return
+		}
+
+		if !HasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access forbidden: missing permission " + permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"errors"
+	"final/models"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// bootstrapAdmin makes sure a fresh installation has an admin. When no admin exists
+// and ADMIN_EMAIL is set, the account with that email is promoted, or created from
+// ADMIN_USERNAME and ADMIN_PASSWORD if it does not exist yet.
+func bootstrapAdmin(db *gorm.DB) error {
+	email := os.Getenv("ADMIN_EMAIL")
+	if email == "" {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var adminRole models.Role
+		if err := tx.Where(models.Role{RoleName: models.RoleAdmin}).FirstOrCreate(&adminRole).Error; err != nil {
+			return err
+		}
+
+		var admins int64
+		if err := tx.Model(&models.User{}).Where("role_id = ?", adminRole.RoleID).Count(&admins).Error; err != nil {
+			return err
+		}
+		if admins > 0 {
+			return nil
+		}
+
+		var user models.User
+		err := tx.Preload("Role").Where("email = ?", email).First(&user).Error
+		previous := ""
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			password := os.Getenv("ADMIN_PASSWORD")
+			if len(password) < 6 {
+				return errors.New("ADMIN_PASSWORD must be at least 6 characters to create the first admin")
+			}
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				return err
+			}
+			username := os.Getenv("ADMIN_USERNAME")
+			if username == "" {
+				username, _, _ = strings.Cut(email, "@")
+			}
+			user = models.User{
+				Username:     username,
+				Email:        email,
+				PasswordHash: string(hashedPassword),
+				RoleID:       adminRole.RoleID,
+				CreatedAt:    time.Now(),
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			previous = user.Role.RoleName
+			if err := tx.Model(&user).Update("role_id", adminRole.RoleID).Error; err != nil {
+				return err
+			}
+		}
+
+		log.Printf("Bootstrapped admin account %s", email)
+		return tx.Create(&models.AuditLog{
+			Action:     "user.role_change",
+			UserID:     &user.UserID,
+			EntityType: "user",
+			EntityID:   user.UserID.String(),
+			Before:     models.JSONMap{"role": previous},
+			After:      models.JSONMap{"role": models.RoleAdmin, "note": "bootstrap"},
+			Timestamp:  time.Now(),
+		}).Error
+	})
+}
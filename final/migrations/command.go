@@ -0,0 +1,126 @@
+package migrations
+
+import (
+	"errors"
+	"final/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sourceDir is where create writes new migration files, relative to the module root
+const sourceDir = "migrations/sql"
+
+// migrationNamePattern restricts new migration names to what loadMigrations accepts
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+const commandUsage = `usage: final migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [N]      roll back the last N migrations (default 1)
+  status        list migrations and whether they are applied
+  create NAME   add empty up and down files for a new migration`
+
+// RunCommand runs the migrate subcommand with the arguments that follow it
+func RunCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(commandUsage)
+	}
+
+	// create only writes files, so it works without a database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(commandUsage)
+		}
+		return create(args[1])
+	}
+
+	switch args[0] {
+	case "up":
+		config.ConnectDatabase()
+		applied, err := Up(config.DB)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+			steps = n
+		}
+		config.ConnectDatabase()
+		rolledBack, err := Down(config.DB, steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("no migrations to roll back")
+		}
+		return nil
+	case "status":
+		config.ConnectDatabase()
+		statuses, err := Status(config.DB)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return errors.New(commandUsage)
+	}
+}
+
+// create writes empty up and down files numbered after the newest migration
+func create(name string) error {
+	name = strings.ToLower(name)
+	if !migrationNamePattern.MatchString(name) {
+		return fmt.Errorf("migration name %q may only contain letters, digits and underscores", name)
+	}
+
+	// Number from the files on disk, which may be newer than the embedded ones
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return fmt.Errorf("run create from the module root: %w", err)
+	}
+	version := 1
+	for _, entry := range entries {
+		if match := migrationFilePattern.FindStringSubmatch(entry.Name()); match != nil {
+			if v, _ := strconv.Atoi(match[1]); v >= version {
+				version = v + 1
+			}
+		}
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(sourceDir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		body := fmt.Sprintf("-- %04d_%s (%s)\n", version, name, direction)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			return err
+		}
+		fmt.Println("created", path)
+	}
+	return nil
+}
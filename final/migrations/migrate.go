@@ -1,11 +1,27 @@
 package migrations
 
 import (
+	"errors"
 	"final/config"
-	"final/models"
+	"log"
 )
 
+// RunMigrations checks the schema on startup and runs the data setup that depends on
+// the environment. The server refuses to start until pending migrations are applied
+// with "go run . migrate up".
 func RunMigrations() {
 	db := config.DB
-	models.Migrate(db)
+	if err := CheckSchema(db); err != nil {
+		if errors.Is(err, ErrSchemaBehind) {
+			log.Fatalf("%v; run \"go run . migrate up\" before starting the server", err)
+		}
+		log.Fatalf("Failed to check database schema: %v", err)
+	}
+
+	if err := seedDemoData(db); err != nil {
+		log.Fatalf("Failed to seed demo data: %v", err)
+	}
+	if err := bootstrapAdmin(db); err != nil {
+		log.Fatalf("Failed to bootstrap admin account: %v", err)
+	}
 }
@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"final/models"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// demoProduct is a product created when SEED_DEMO_DATA is enabled. Prices are
+// parsed at seed time, once the store currency is known.
+type demoProduct struct {
+	Name        string
+	Description string
+	Price       string
+	Stock       int
+}
+
+// demoProducts are created when SEED_DEMO_DATA is enabled, keyed by category name
+var demoProducts = map[string][]demoProduct{
+	"Electronics": {
+		{Name: "Wireless Headphones", Description: "Over-ear Bluetooth headphones with noise cancelling", Price: "129.99", Stock: 25},
+		{Name: "USB-C Charger", Description: "65W fast charger with two ports", Price: "39.90", Stock: 100},
+	},
+	"Clothing": {
+		{Name: "Cotton T-Shirt", Description: "Classic crew neck t-shirt", Price: "14.50", Stock: 200},
+	},
+	"Books": {
+		{Name: "The Go Programming Language", Description: "A thorough introduction to Go", Price: "34.99", Stock: 40},
+	},
+}
+
+// seedDemoData creates demo products when SEED_DEMO_DATA is true. Roles and
+// categories are seeded by migrations. It is safe to run on every start.
+func seedDemoData(db *gorm.DB) error {
+	if seedDemo, _ := strconv.ParseBool(os.Getenv("SEED_DEMO_DATA")); !seedDemo {
+		return nil
+	}
+	return db.Transaction(seedDemoProducts)
+}
+
+// seedDemoProducts creates each demo product unless one with the same name exists in its category
+func seedDemoProducts(tx *gorm.DB) error {
+	for categoryName, products := range demoProducts {
+		var category models.Category
+		if err := tx.Where("name = ?", categoryName).First(&category).Error; err != nil {
+			return err
+		}
+		for _, demo := range products {
+			price, err := models.ParseMoney(demo.Price)
+			if err != nil {
+				return err
+			}
+			product := models.Product{
+				Name:        demo.Name,
+				Description: demo.Description,
+				Price:       price,
+				Stock:       demo.Stock,
+				CategoryID:  category.CategoryID,
+			}
+			if err := tx.Where(models.Product{Name: product.Name, CategoryID: category.CategoryID}).
+				FirstOrCreate(&product).Error; err != nil {
+				return err
+			}
+			// The product's stock is held by its single default variant
+			variant := models.ProductVariant{
+				ProductID: product.ProductID,
+				SKU:       models.DefaultSKU(product.ProductID),
+				Options:   models.JSONMap{},
+				Stock:     product.Stock,
+				IsDefault: true,
+			}
+			if err := tx.Where(models.ProductVariant{ProductID: product.ProductID}).
+				FirstOrCreate(&variant).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
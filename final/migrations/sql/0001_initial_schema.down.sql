@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS caches;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS shopping_carts;
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS order_status_histories;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS user_addresses;
+DROP TABLE IF EXISTS product_images;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS roles;
+DROP EXTENSION IF EXISTS "uuid-ossp";
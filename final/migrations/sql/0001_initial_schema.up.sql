@@ -0,0 +1,279 @@
+-- Initial schema. Statements use IF NOT EXISTS so databases created by the
+-- old AutoMigrate setup can adopt versioned migrations. CREATE TABLE skips tables
+-- that already exist, so columns added since the first AutoMigrate schema are also
+-- added on their own.
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS roles (
+    role_id   uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    role_name varchar(50) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    user_id       uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username      varchar(100) NOT NULL UNIQUE,
+    password_hash varchar(255) NOT NULL,
+    email         varchar(100) NOT NULL UNIQUE,
+    role_id       uuid NOT NULL REFERENCES roles (role_id),
+    created_at    timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    category_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name        varchar(100) NOT NULL,
+    description text
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    product_id  uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name        varchar(100) NOT NULL,
+    description text,
+    price       numeric NOT NULL,
+    stock       bigint NOT NULL,
+    category_id uuid NOT NULL REFERENCES categories (category_id),
+    created_at  timestamptz
+);
+
+-- AutoMigrate did not keep role and category names unique, so an adopted database
+-- may hold several rows per name. Keep the first of each and move users and
+-- products over to it before the unique indexes are created.
+UPDATE users
+SET role_id = kept.role_id
+FROM roles r, (
+    SELECT role_name, min(role_id::text)::uuid AS role_id
+    FROM roles
+    GROUP BY role_name
+    HAVING count(*) > 1
+) kept
+WHERE users.role_id = r.role_id
+    AND r.role_name = kept.role_name
+    AND r.role_id <> kept.role_id;
+
+DELETE FROM roles
+USING roles kept
+WHERE kept.role_name = roles.role_name
+    AND kept.role_id::text < roles.role_id::text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_role_name ON roles (role_name);
+
+UPDATE products
+SET category_id = kept.category_id
+FROM categories c, (
+    SELECT name, min(category_id::text)::uuid AS category_id
+    FROM categories
+    GROUP BY name
+    HAVING count(*) > 1
+) kept
+WHERE products.category_id = c.category_id
+    AND c.name = kept.name
+    AND c.category_id <> kept.category_id;
+
+DELETE FROM categories
+USING categories kept
+WHERE kept.name = categories.name
+    AND kept.category_id::text < categories.category_id::text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories (name);
+
+CREATE TABLE IF NOT EXISTS product_images (
+    image_id      uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id    uuid NOT NULL REFERENCES products (product_id),
+    image_url     text NOT NULL,
+    thumbnail_url text,
+    storage_key   varchar(255),
+    thumbnail_key varchar(255),
+    content_type  varchar(50),
+    size          bigint,
+    position      bigint NOT NULL DEFAULT 0,
+    created_at    timestamptz
+);
+ALTER TABLE product_images
+    ADD COLUMN IF NOT EXISTS thumbnail_url text,
+    ADD COLUMN IF NOT EXISTS storage_key   varchar(255),
+    ADD COLUMN IF NOT EXISTS thumbnail_key varchar(255),
+    ADD COLUMN IF NOT EXISTS content_type  varchar(50),
+    ADD COLUMN IF NOT EXISTS size          bigint,
+    ADD COLUMN IF NOT EXISTS position      bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images (product_id);
+
+CREATE TABLE IF NOT EXISTS user_addresses (
+    address_id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id             uuid NOT NULL REFERENCES users (user_id),
+    label               varchar(50),
+    recipient_name      varchar(100),
+    street              varchar(255),
+    city                varchar(100),
+    state               varchar(100),
+    zip_code            varchar(20),
+    country             varchar(2),
+    is_default_shipping boolean NOT NULL DEFAULT false,
+    is_default_billing  boolean NOT NULL DEFAULT false,
+    created_at          timestamptz,
+    updated_at          timestamptz
+);
+ALTER TABLE user_addresses
+    ADD COLUMN IF NOT EXISTS label               varchar(50),
+    ADD COLUMN IF NOT EXISTS recipient_name      varchar(100),
+    ADD COLUMN IF NOT EXISTS country             varchar(2),
+    ADD COLUMN IF NOT EXISTS is_default_shipping boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS is_default_billing  boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS created_at          timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at          timestamptz;
+-- Users once had a single address; drop any unique constraint or index on user_id
+DO $$
+DECLARE
+    user_id_key int2[] := ARRAY[(SELECT attnum FROM pg_attribute
+        WHERE attrelid = 'user_addresses'::regclass AND attname = 'user_id')];
+    object_name text;
+BEGIN
+    FOR object_name IN
+        SELECT conname FROM pg_constraint
+        WHERE conrelid = 'user_addresses'::regclass AND contype = 'u' AND conkey = user_id_key
+    LOOP
+        EXECUTE format('ALTER TABLE user_addresses DROP CONSTRAINT %I', object_name);
+    END LOOP;
+    FOR object_name IN
+        SELECT index_class.relname FROM pg_index
+        JOIN pg_class index_class ON index_class.oid = pg_index.indexrelid
+        WHERE pg_index.indrelid = 'user_addresses'::regclass AND pg_index.indisunique
+            AND pg_index.indkey::int2[] = user_id_key
+    LOOP
+        EXECUTE format('DROP INDEX %I', object_name);
+    END LOOP;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_user_addresses_user_id ON user_addresses (user_id);
+
+CREATE TABLE IF NOT EXISTS orders (
+    order_id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id                 uuid NOT NULL REFERENCES users (user_id),
+    order_date              timestamptz,
+    status                  varchar(50) NOT NULL,
+    total_amount            numeric NOT NULL,
+    shipping_recipient_name varchar(100),
+    shipping_street         varchar(255),
+    shipping_city           varchar(100),
+    shipping_state          varchar(100),
+    shipping_zip_code       varchar(20),
+    shipping_country        varchar(2),
+    billing_recipient_name  varchar(100),
+    billing_street          varchar(255),
+    billing_city            varchar(100),
+    billing_state           varchar(100),
+    billing_zip_code        varchar(20),
+    billing_country         varchar(2)
+);
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS shipping_recipient_name varchar(100),
+    ADD COLUMN IF NOT EXISTS shipping_street         varchar(255),
+    ADD COLUMN IF NOT EXISTS shipping_city           varchar(100),
+    ADD COLUMN IF NOT EXISTS shipping_state          varchar(100),
+    ADD COLUMN IF NOT EXISTS shipping_zip_code       varchar(20),
+    ADD COLUMN IF NOT EXISTS shipping_country        varchar(2),
+    ADD COLUMN IF NOT EXISTS billing_recipient_name  varchar(100),
+    ADD COLUMN IF NOT EXISTS billing_street          varchar(255),
+    ADD COLUMN IF NOT EXISTS billing_city            varchar(100),
+    ADD COLUMN IF NOT EXISTS billing_state           varchar(100),
+    ADD COLUMN IF NOT EXISTS billing_zip_code        varchar(20),
+    ADD COLUMN IF NOT EXISTS billing_country         varchar(2);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    order_item_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id      uuid NOT NULL REFERENCES orders (order_id),
+    product_id    uuid NOT NULL REFERENCES products (product_id),
+    quantity      bigint NOT NULL,
+    price         numeric NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_status_histories (
+    history_id  uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id    uuid NOT NULL REFERENCES orders (order_id),
+    from_status varchar(50),
+    to_status   varchar(50) NOT NULL,
+    changed_by  uuid NOT NULL,
+    note        text,
+    changed_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_histories_order_id ON order_status_histories (order_id);
+
+CREATE TABLE IF NOT EXISTS payments (
+    payment_id      uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id        uuid NOT NULL REFERENCES orders (order_id),
+    amount          numeric NOT NULL,
+    payment_date    timestamptz,
+    payment_method  varchar(50) NOT NULL,
+    provider        varchar(50),
+    reference       varchar(255),
+    idempotency_key varchar(255),
+    refund_of_id    uuid REFERENCES payments (payment_id)
+);
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS provider        varchar(50),
+    ADD COLUMN IF NOT EXISTS reference       varchar(255),
+    ADD COLUMN IF NOT EXISTS idempotency_key varchar(255),
+    ADD COLUMN IF NOT EXISTS refund_of_id    uuid REFERENCES payments (payment_id);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_idempotency_key ON payments (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_payments_refund_of_id ON payments (refund_of_id);
+
+CREATE TABLE IF NOT EXISTS shopping_carts (
+    cart_id    uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL UNIQUE REFERENCES users (user_id),
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    cart_item_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cart_id      uuid NOT NULL REFERENCES shopping_carts (cart_id),
+    product_id   uuid NOT NULL REFERENCES products (product_id),
+    quantity     bigint NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    review_id  uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id uuid NOT NULL REFERENCES products (product_id),
+    user_id    uuid NOT NULL REFERENCES users (user_id),
+    rating     bigint NOT NULL,
+    comment    text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS updated_at timestamptz;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_product_user ON reviews (product_id, user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL REFERENCES users (user_id),
+    user_agent varchar(255),
+    ip_address varchar(45),
+    created_at timestamptz,
+    expires_at timestamptz,
+    revoked_at timestamptz
+);
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS user_agent varchar(255),
+    ADD COLUMN IF NOT EXISTS ip_address varchar(45),
+    ADD COLUMN IF NOT EXISTS revoked_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_id   uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id uuid NOT NULL REFERENCES sessions (session_id),
+    token_hash varchar(64) NOT NULL,
+    created_at timestamptz,
+    used_at    timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    log_id    uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action    text,
+    user_id   uuid REFERENCES users (user_id),
+    timestamp timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS caches (
+    cache_key       varchar(255) PRIMARY KEY,
+    cache_value     text,
+    expiration_time timestamptz
+);
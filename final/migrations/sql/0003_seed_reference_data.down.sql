@@ -0,0 +1,5 @@
+-- Roles stay since every user references one; only seeded categories that
+-- no product uses are removed
+DELETE FROM categories c
+WHERE c.name IN ('Electronics', 'Clothing', 'Home & Kitchen', 'Books', 'Sports & Outdoors')
+  AND NOT EXISTS (SELECT 1 FROM products p WHERE p.category_id = c.category_id);
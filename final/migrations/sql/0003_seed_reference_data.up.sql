@@ -0,0 +1,13 @@
+-- Roles and categories the API depends on
+INSERT INTO roles (role_name) VALUES
+    ('user'),
+    ('admin')
+ON CONFLICT (role_name) DO NOTHING;
+
+INSERT INTO categories (name, description) VALUES
+    ('Electronics', 'Phones, computers and accessories'),
+    ('Clothing', 'Apparel, shoes and accessories'),
+    ('Home & Kitchen', 'Furniture, appliances and kitchenware'),
+    ('Books', 'Printed books and e-books'),
+    ('Sports & Outdoors', 'Sports equipment and outdoor gear')
+ON CONFLICT (name) DO NOTHING;
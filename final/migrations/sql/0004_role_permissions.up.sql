@@ -0,0 +1,29 @@
+CREATE TABLE permissions (
+    permission_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name          varchar(100) NOT NULL,
+    description   text
+);
+CREATE UNIQUE INDEX idx_permissions_name ON permissions (name);
+
+CREATE TABLE role_permissions (
+    role_id       uuid NOT NULL REFERENCES roles (role_id) ON DELETE CASCADE,
+    permission_id uuid NOT NULL REFERENCES permissions (permission_id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('product:write', 'Create, update and delete products and their images'),
+    ('category:write', 'Create, update and delete categories'),
+    ('order:manage', 'View any order and change its status'),
+    ('order:refund', 'Refund payments'),
+    ('user:manage', 'Assign roles to users'),
+    ('role:manage', 'Create roles and manage their permissions'),
+    ('report:read', 'View sales reports')
+ON CONFLICT (name) DO NOTHING;
+
+-- Admins keep every capability they had before permissions existed
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r CROSS JOIN permissions p
+WHERE r.role_name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+DELETE FROM permissions WHERE name = 'audit:read';
+
+DROP INDEX IF EXISTS idx_audit_logs_timestamp;
+DROP INDEX IF EXISTS idx_audit_logs_entity;
+DROP INDEX IF EXISTS idx_audit_logs_user_id;
+
+ALTER TABLE audit_logs
+    DROP COLUMN request_id,
+    DROP COLUMN ip_address,
+    DROP COLUMN status_code,
+    DROP COLUMN path,
+    DROP COLUMN method,
+    DROP COLUMN after,
+    DROP COLUMN before,
+    DROP COLUMN entity_id,
+    DROP COLUMN entity_type;
@@ -0,0 +1,24 @@
+ALTER TABLE audit_logs
+    ADD COLUMN entity_type varchar(50),
+    ADD COLUMN entity_id   varchar(64),
+    ADD COLUMN before      jsonb,
+    ADD COLUMN after       jsonb,
+    ADD COLUMN method      varchar(10),
+    ADD COLUMN path        text,
+    ADD COLUMN status_code bigint,
+    ADD COLUMN ip_address  varchar(45),
+    ADD COLUMN request_id  varchar(64);
+
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX idx_audit_logs_timestamp ON audit_logs (timestamp);
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'View the audit log')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r JOIN permissions p ON p.name = 'audit:read'
+WHERE r.role_name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cache_tags;
+DROP INDEX IF EXISTS idx_caches_expiration_time;
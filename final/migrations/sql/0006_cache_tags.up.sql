@@ -0,0 +1,8 @@
+CREATE INDEX idx_caches_expiration_time ON caches (expiration_time);
+
+CREATE TABLE cache_tags (
+    tag       varchar(255) NOT NULL,
+    cache_key varchar(255) NOT NULL REFERENCES caches (cache_key) ON DELETE CASCADE,
+    PRIMARY KEY (tag, cache_key)
+);
+CREATE INDEX idx_cache_tags_cache_key ON cache_tags (cache_key);
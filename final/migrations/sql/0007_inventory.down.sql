@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name = 'inventory:manage';
+
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS stock_reservations;
+DROP TABLE IF EXISTS stock_movements;
+
+ALTER TABLE products DROP COLUMN IF EXISTS low_stock_threshold;
@@ -0,0 +1,48 @@
+ALTER TABLE products ADD COLUMN low_stock_threshold bigint;
+
+CREATE TABLE stock_movements (
+    movement_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id  uuid NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+    quantity    bigint NOT NULL,
+    stock_after bigint NOT NULL,
+    reason      varchar(30) NOT NULL,
+    note        text,
+    order_id    uuid REFERENCES orders (order_id),
+    actor_id    uuid,
+    created_at  timestamptz
+);
+CREATE INDEX idx_stock_movements_product_id ON stock_movements (product_id);
+CREATE INDEX idx_stock_movements_order_id ON stock_movements (order_id);
+
+CREATE TABLE stock_reservations (
+    reservation_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id        uuid NOT NULL REFERENCES users (user_id),
+    product_id     uuid NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+    quantity       bigint NOT NULL,
+    expires_at     timestamptz,
+    created_at     timestamptz
+);
+CREATE INDEX idx_stock_reservations_user_id ON stock_reservations (user_id);
+CREATE INDEX idx_stock_reservations_expires_at ON stock_reservations (expires_at);
+
+CREATE TABLE notifications (
+    notification_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id         uuid NOT NULL REFERENCES users (user_id),
+    type            varchar(50) NOT NULL,
+    title           varchar(255) NOT NULL,
+    message         text,
+    data            jsonb,
+    read_at         timestamptz,
+    created_at      timestamptz
+);
+CREATE INDEX idx_notifications_user_id ON notifications (user_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('inventory:manage', 'Adjust stock, view the stock ledger and receive low-stock alerts')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r JOIN permissions p ON p.name = 'inventory:manage'
+WHERE r.role_name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,14 @@
+DELETE FROM permissions WHERE name = 'promotion:manage';
+
+ALTER TABLE shopping_carts DROP COLUMN IF EXISTS coupon_code;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS coupon_code,
+    DROP COLUMN IF EXISTS coupon_id,
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS subtotal;
+
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupon_categories;
+DROP TABLE IF EXISTS coupon_products;
+DROP TABLE IF EXISTS coupons;
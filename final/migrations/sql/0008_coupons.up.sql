@@ -0,0 +1,62 @@
+CREATE TABLE coupons (
+    coupon_id       uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code            varchar(50) NOT NULL,
+    description     text,
+    discount_type   varchar(20) NOT NULL,
+    percent_off     bigint NOT NULL DEFAULT 0,
+    amount_off      numeric NOT NULL DEFAULT 0,
+    min_order_value numeric NOT NULL DEFAULT 0,
+    usage_limit     bigint,
+    per_user_limit  bigint,
+    usage_count     bigint NOT NULL DEFAULT 0,
+    starts_at       timestamptz,
+    ends_at         timestamptz,
+    active          boolean NOT NULL DEFAULT true,
+    created_at      timestamptz
+);
+CREATE UNIQUE INDEX idx_coupons_code ON coupons (code);
+
+CREATE TABLE coupon_products (
+    coupon_id  uuid NOT NULL REFERENCES coupons (coupon_id) ON DELETE CASCADE,
+    product_id uuid NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+    PRIMARY KEY (coupon_id, product_id)
+);
+
+CREATE TABLE coupon_categories (
+    coupon_id   uuid NOT NULL REFERENCES coupons (coupon_id) ON DELETE CASCADE,
+    category_id uuid NOT NULL REFERENCES categories (category_id) ON DELETE CASCADE,
+    PRIMARY KEY (coupon_id, category_id)
+);
+
+CREATE TABLE coupon_redemptions (
+    redemption_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    coupon_id     uuid NOT NULL REFERENCES coupons (coupon_id),
+    user_id       uuid NOT NULL REFERENCES users (user_id),
+    order_id      uuid NOT NULL REFERENCES orders (order_id),
+    discount      numeric NOT NULL,
+    created_at    timestamptz
+);
+CREATE INDEX idx_coupon_redemptions_coupon_id ON coupon_redemptions (coupon_id);
+CREATE INDEX idx_coupon_redemptions_user_id ON coupon_redemptions (user_id);
+CREATE UNIQUE INDEX idx_coupon_redemptions_order_id ON coupon_redemptions (order_id);
+
+-- Orders placed before coupons existed were never discounted
+ALTER TABLE orders
+    ADD COLUMN subtotal        numeric,
+    ADD COLUMN discount_amount numeric NOT NULL DEFAULT 0,
+    ADD COLUMN coupon_id       uuid REFERENCES coupons (coupon_id),
+    ADD COLUMN coupon_code     varchar(50);
+UPDATE orders SET subtotal = total_amount;
+ALTER TABLE orders ALTER COLUMN subtotal SET NOT NULL;
+
+ALTER TABLE shopping_carts ADD COLUMN coupon_code varchar(50);
+
+INSERT INTO permissions (name, description) VALUES
+    ('promotion:manage', 'Create, edit and delete coupons')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r JOIN permissions p ON p.name = 'promotion:manage'
+WHERE r.role_name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,14 @@
+DELETE FROM permissions WHERE name = 'pricing:manage';
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tax_rate,
+    DROP COLUMN IF EXISTS tax_region,
+    DROP COLUMN IF EXISTS shipping_method,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS shipping_amount;
+
+DROP TABLE IF EXISTS shipping_tiers;
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS tax_rates;
+
+ALTER TABLE products DROP COLUMN IF EXISTS weight_grams;
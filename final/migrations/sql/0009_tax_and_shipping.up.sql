@@ -0,0 +1,58 @@
+ALTER TABLE products ADD COLUMN weight_grams bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE tax_rates (
+    tax_rate_id  uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name         varchar(100) NOT NULL,
+    country      varchar(2) NOT NULL,
+    state        varchar(100) NOT NULL DEFAULT '',
+    zip_prefix   varchar(20) NOT NULL DEFAULT '',
+    rate         numeric NOT NULL CHECK (rate >= 0 AND rate < 1),
+    tax_shipping boolean NOT NULL DEFAULT false,
+    created_at   timestamptz
+);
+CREATE UNIQUE INDEX idx_tax_rates_region ON tax_rates (country, state, zip_prefix);
+
+CREATE TABLE shipping_methods (
+    shipping_method_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code               varchar(50) NOT NULL,
+    name               varchar(100) NOT NULL,
+    basis              varchar(20) NOT NULL,
+    free_threshold     numeric,
+    active             boolean NOT NULL DEFAULT true,
+    created_at         timestamptz
+);
+CREATE UNIQUE INDEX idx_shipping_methods_code ON shipping_methods (code);
+
+CREATE TABLE shipping_tiers (
+    tier_id            uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    shipping_method_id uuid NOT NULL REFERENCES shipping_methods (shipping_method_id) ON DELETE CASCADE,
+    min_weight_grams   bigint NOT NULL DEFAULT 0,
+    min_subtotal       numeric NOT NULL DEFAULT 0,
+    cost               numeric NOT NULL
+);
+CREATE INDEX idx_shipping_tiers_shipping_method_id ON shipping_tiers (shipping_method_id);
+
+-- Orders placed before this migration carried no shipping or tax
+ALTER TABLE orders
+    ADD COLUMN shipping_amount numeric NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount      numeric NOT NULL DEFAULT 0,
+    ADD COLUMN shipping_method varchar(50),
+    ADD COLUMN tax_region      varchar(150),
+    ADD COLUMN tax_rate        numeric NOT NULL DEFAULT 0;
+
+-- A flat standard rate keeps checkout working until real tiers are configured
+INSERT INTO shipping_methods (code, name, basis, active, created_at)
+VALUES ('standard', 'Standard shipping', 'price', true, now())
+ON CONFLICT (code) DO NOTHING;
+INSERT INTO shipping_tiers (shipping_method_id, min_subtotal, cost)
+SELECT shipping_method_id, 0, 0 FROM shipping_methods WHERE code = 'standard';
+
+INSERT INTO permissions (name, description) VALUES
+    ('pricing:manage', 'Manage tax rates and shipping methods')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r JOIN permissions p ON p.name = 'pricing:manage'
+WHERE r.role_name = 'admin'
+ON CONFLICT DO NOTHING;
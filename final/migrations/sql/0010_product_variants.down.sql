@@ -0,0 +1,30 @@
+-- Product stock already holds the total of its variants, so it needs no rollup
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS variant_id;
+
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS options,
+    DROP COLUMN IF EXISTS sku,
+    DROP COLUMN IF EXISTS variant_id;
+
+-- Without variants a cart holds one line per product, so lines for different
+-- variants of a product are folded into one
+UPDATE cart_items
+SET quantity = merged.quantity
+FROM (
+    SELECT min(cart_item_id::text)::uuid AS cart_item_id, sum(quantity) AS quantity
+    FROM cart_items
+    GROUP BY cart_id, product_id
+    HAVING count(*) > 1
+) merged
+WHERE cart_items.cart_item_id = merged.cart_item_id;
+
+DELETE FROM cart_items
+USING cart_items kept
+WHERE kept.cart_id = cart_items.cart_id
+    AND kept.product_id = cart_items.product_id
+    AND kept.cart_item_id::text < cart_items.cart_item_id::text;
+
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+
+DROP TABLE IF EXISTS product_variants;
@@ -0,0 +1,44 @@
+CREATE TABLE product_variants (
+    variant_id   uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id   uuid NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+    sku          varchar(64) NOT NULL,
+    options      jsonb NOT NULL DEFAULT '{}',
+    price        numeric,
+    weight_grams bigint,
+    stock        bigint NOT NULL DEFAULT 0,
+    is_default   boolean NOT NULL DEFAULT false,
+    created_at   timestamptz
+);
+CREATE UNIQUE INDEX idx_product_variants_sku ON product_variants (sku);
+CREATE INDEX idx_product_variants_product_id ON product_variants (product_id);
+-- A product cannot offer the same combination of options twice
+CREATE UNIQUE INDEX idx_product_variants_options ON product_variants (product_id, options);
+CREATE UNIQUE INDEX idx_product_variants_default ON product_variants (product_id) WHERE is_default;
+
+-- Every existing product becomes a single default variant holding all of its stock
+INSERT INTO product_variants (product_id, sku, options, stock, is_default, created_at)
+SELECT product_id, 'P-' || upper(replace(product_id::text, '-', '')), '{}', stock, true, created_at
+FROM products;
+
+ALTER TABLE cart_items ADD COLUMN variant_id uuid REFERENCES product_variants (variant_id) ON DELETE CASCADE;
+UPDATE cart_items SET variant_id = v.variant_id
+FROM product_variants v WHERE v.product_id = cart_items.product_id;
+ALTER TABLE cart_items ALTER COLUMN variant_id SET NOT NULL;
+
+ALTER TABLE order_items
+    ADD COLUMN variant_id uuid REFERENCES product_variants (variant_id) ON DELETE SET NULL,
+    ADD COLUMN sku        varchar(64),
+    ADD COLUMN options    jsonb;
+UPDATE order_items SET variant_id = v.variant_id, sku = v.sku, options = v.options
+FROM product_variants v WHERE v.product_id = order_items.product_id;
+
+ALTER TABLE stock_movements ADD COLUMN variant_id uuid REFERENCES product_variants (variant_id) ON DELETE CASCADE;
+UPDATE stock_movements SET variant_id = v.variant_id
+FROM product_variants v WHERE v.product_id = stock_movements.product_id;
+ALTER TABLE stock_movements ALTER COLUMN variant_id SET NOT NULL;
+CREATE INDEX idx_stock_movements_variant_id ON stock_movements (variant_id);
+
+ALTER TABLE stock_reservations ADD COLUMN variant_id uuid REFERENCES product_variants (variant_id) ON DELETE CASCADE;
+UPDATE stock_reservations SET variant_id = v.variant_id
+FROM product_variants v WHERE v.product_id = stock_reservations.product_id;
+ALTER TABLE stock_reservations ALTER COLUMN variant_id SET NOT NULL;
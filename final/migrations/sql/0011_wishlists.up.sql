@@ -0,0 +1,24 @@
+CREATE TABLE wishlists (
+    wishlist_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id     uuid NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    name        varchar(100) NOT NULL,
+    share_token varchar(64),
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE UNIQUE INDEX idx_wishlists_user_name ON wishlists (user_id, name);
+CREATE UNIQUE INDEX idx_wishlists_share_token ON wishlists (share_token);
+
+CREATE TABLE wishlist_items (
+    wishlist_item_id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wishlist_id      uuid NOT NULL REFERENCES wishlists (wishlist_id) ON DELETE CASCADE,
+    product_id       uuid NOT NULL REFERENCES products (product_id) ON DELETE CASCADE,
+    variant_id       uuid REFERENCES product_variants (variant_id) ON DELETE CASCADE,
+    created_at       timestamptz
+);
+CREATE INDEX idx_wishlist_items_wishlist_id ON wishlist_items (wishlist_id);
+-- Stock and price notifications look items up by product
+CREATE INDEX idx_wishlist_items_product_id ON wishlist_items (product_id);
+-- A product, or one of its variants, is on a wishlist at most once
+CREATE UNIQUE INDEX idx_wishlist_items_entry ON wishlist_items
+    (wishlist_id, product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'));
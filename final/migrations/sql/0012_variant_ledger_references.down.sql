@@ -0,0 +1,9 @@
+ALTER TABLE stock_reservations
+    DROP CONSTRAINT stock_reservations_variant_id_fkey,
+    ADD CONSTRAINT stock_reservations_variant_id_fkey
+        FOREIGN KEY (variant_id) REFERENCES product_variants (variant_id) ON DELETE CASCADE;
+
+ALTER TABLE stock_movements
+    DROP CONSTRAINT stock_movements_variant_id_fkey,
+    ADD CONSTRAINT stock_movements_variant_id_fkey
+        FOREIGN KEY (variant_id) REFERENCES product_variants (variant_id) ON DELETE CASCADE;
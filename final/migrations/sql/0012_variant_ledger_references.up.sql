@@ -0,0 +1,13 @@
+-- Deleting a variant must not erase its stock ledger or drop the reservations holding
+-- its stock. NO ACTION refuses such deletes like RESTRICT, but checks at the end of
+-- the statement, so deleting a whole product still cascades to its variants together
+-- with their ledger and reservations.
+ALTER TABLE stock_movements
+    DROP CONSTRAINT stock_movements_variant_id_fkey,
+    ADD CONSTRAINT stock_movements_variant_id_fkey
+        FOREIGN KEY (variant_id) REFERENCES product_variants (variant_id);
+
+ALTER TABLE stock_reservations
+    DROP CONSTRAINT stock_reservations_variant_id_fkey,
+    ADD CONSTRAINT stock_reservations_variant_id_fkey
+        FOREIGN KEY (variant_id) REFERENCES product_variants (variant_id);
@@ -0,0 +1,25 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE order_items ADD COLUMN discount_amount numeric NOT NULL DEFAULT 0;
+
+-- Earlier orders only stored the order's discount; spread it over the order's items
+-- in proportion to their totals, rounded down to cents. As in promotions.allocate, one
+-- item per order takes the remainder so the shares add up to the discount exactly.
+WITH shares AS (
+    SELECT order_items.order_item_id,
+        orders.discount_amount,
+        trunc(orders.discount_amount * order_items.price * order_items.quantity / orders.subtotal, 2) AS share,
+        row_number() OVER (PARTITION BY order_items.order_id ORDER BY order_items.order_item_id DESC) AS position,
+        sum(trunc(orders.discount_amount * order_items.price * order_items.quantity / orders.subtotal, 2))
+            OVER (PARTITION BY order_items.order_id) AS allocated
+    FROM order_items
+    JOIN orders ON orders.order_id = order_items.order_id
+    WHERE orders.discount_amount <> 0
+        AND orders.subtotal > 0
+)
+UPDATE order_items
+SET discount_amount = CASE
+    WHEN shares.position = 1 THEN shares.share + shares.discount_amount - shares.allocated
+    ELSE shares.share
+END
+FROM shares
+WHERE shares.order_item_id = order_items.order_item_id;
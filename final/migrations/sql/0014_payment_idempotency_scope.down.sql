@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_refund_idempotency_key;
+DROP INDEX IF EXISTS idx_payments_charge_idempotency_key;
+CREATE UNIQUE INDEX idx_payments_idempotency_key ON payments (idempotency_key);
@@ -0,0 +1,8 @@
+-- Idempotency keys were unique across all payments, so a client reusing a key to
+-- refund an order got the order's charge back. Keys are now scoped to an order and
+-- to charges or refunds.
+DROP INDEX IF EXISTS idx_payments_idempotency_key;
+CREATE UNIQUE INDEX idx_payments_charge_idempotency_key ON payments (order_id, idempotency_key)
+    WHERE refund_of_id IS NULL;
+CREATE UNIQUE INDEX idx_payments_refund_idempotency_key ON payments (order_id, idempotency_key)
+    WHERE refund_of_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- Concurrent adds could leave a cart with several lines for one variant. Fold them
+-- into one line; checkout checks the merged quantity against stock.
+UPDATE cart_items
+SET quantity = merged.quantity
+FROM (
+    SELECT min(cart_item_id::text)::uuid AS cart_item_id, sum(quantity) AS quantity
+    FROM cart_items
+    GROUP BY cart_id, variant_id
+    HAVING count(*) > 1
+) merged
+WHERE cart_items.cart_item_id = merged.cart_item_id;
+
+DELETE FROM cart_items
+USING cart_items kept
+WHERE kept.cart_id = cart_items.cart_id
+    AND kept.variant_id = cart_items.variant_id
+    AND kept.cart_item_id::text < cart_items.cart_item_id::text;
+
+CREATE UNIQUE INDEX idx_cart_items_cart_variant ON cart_items (cart_id, variant_id);
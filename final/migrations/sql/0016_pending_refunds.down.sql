@@ -0,0 +1,5 @@
+-- Without a status every stored payment counts as completed, so refunds that never
+-- went through are dropped
+DROP INDEX IF EXISTS idx_payments_pending;
+DELETE FROM payments WHERE status <> 'completed';
+ALTER TABLE payments DROP COLUMN IF EXISTS status;
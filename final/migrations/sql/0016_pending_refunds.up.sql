@@ -0,0 +1,5 @@
+-- Refunds are recorded as pending before the provider is called and settled once
+-- the transaction that issued them has committed. Existing payments went through
+-- the provider before they were stored.
+ALTER TABLE payments ADD COLUMN status varchar(20) NOT NULL DEFAULT 'completed';
+CREATE INDEX idx_payments_pending ON payments (order_id) WHERE status = 'pending';
@@ -1,162 +1,541 @@
 package models
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type User struct {
-	UserID       uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Username     string       `gorm:"type:varchar(100);unique;not null"`
-	PasswordHash string       `gorm:"type:varchar(255);not null"`
-	Email        string       `gorm:"type:varchar(100);unique;not null"`
-	Address      UserAddress  `gorm:"foreignKey:UserID"`
-	RoleID       uuid.UUID    `gorm:"type:uuid;not null"`
-	Role         Role         `gorm:"foreignKey:RoleID"`
-	ShoppingCart ShoppingCart `gorm:"foreignKey:UserID"`
-	Orders       []Order      `gorm:"foreignKey:UserID"`
-	Reviews      []Review     `gorm:"foreignKey:UserID"`
+	UserID       uuid.UUID     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Username     string        `gorm:"type:varchar(100);unique;not null"`
+	PasswordHash string        `gorm:"type:varchar(255);not null"`
+	Email        string        `gorm:"type:varchar(100);unique;not null"`
+	Addresses    []UserAddress `gorm:"foreignKey:UserID"`
+	RoleID       uuid.UUID     `gorm:"type:uuid;not null"`
+	Role         Role          `gorm:"foreignKey:RoleID"`
+	ShoppingCart ShoppingCart  `gorm:"foreignKey:UserID"`
+	Orders       []Order       `gorm:"foreignKey:UserID"`
+	Reviews      []Review      `gorm:"foreignKey:UserID"`
 
 	CreatedAt time.Time
 }
 
 type Product struct {
-	ProductID   uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Name        string         `gorm:"type:varchar(100);not null"`
-	Description string         `gorm:"type:text"`
-	Price       float64        `gorm:"type:numeric;not null"`
-	Stock       int            `gorm:"not null"`
-	CategoryID  uuid.UUID      `gorm:"type:uuid;not null"`
-	Category    Category       `gorm:"foreignKey:CategoryID"`
-	Images      []ProductImage `gorm:"foreignKey:ProductID"`
-	Reviews     []Review       `gorm:"foreignKey:ProductID"`
-	CreatedAt   time.Time
+	ProductID   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name        string    `gorm:"type:varchar(100);not null"`
+	Description string    `gorm:"type:text"`
+	// Price is the base price; variants may override it
+	Price Money `gorm:"type:numeric;not null"`
+	// Stock is the total stock of the product's variants
+	Stock int `gorm:"not null"`
+	// WeightGrams is the shipping weight of one unit; variants may override it
+	WeightGrams int `gorm:"not null"`
+	// LowStockThreshold overrides the global low-stock threshold when set
+	LowStockThreshold *int
+	CategoryID        uuid.UUID        `gorm:"type:uuid;not null"`
+	Category          Category         `gorm:"foreignKey:CategoryID"`
+	Images            []ProductImage   `gorm:"foreignKey:ProductID"`
+	Variants          []ProductVariant `gorm:"foreignKey:ProductID"`
+	Reviews           []Review         `gorm:"foreignKey:ProductID"`
+	CreatedAt         time.Time
+}
+
+// ProductVariant is one purchasable version of a product, such as a size and color.
+// Every product has at least one variant; a product without options has a single
+// default variant with empty Options.
+type ProductVariant struct {
+	VariantID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;index"`
+	SKU       string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	// Options holds the variant's attribute values, e.g. {"size": "M", "color": "red"}
+	Options JSONMap `gorm:"type:jsonb;not null"`
+	// Price and WeightGrams override the product's when set
+	Price       *Money `gorm:"type:numeric"`
+	WeightGrams *int
+	Stock       int `gorm:"not null"`
+	// IsDefault marks the variant shown first; each product has exactly one
+	IsDefault bool `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// EffectivePrice returns the variant's price, falling back to the product's
+func (v ProductVariant) EffectivePrice(product Product) Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
+
+// FromPrice returns the lowest effective price among the product's loaded variants,
+// or the base price when none are loaded. Listings filter and sort on this price.
+func (p Product) FromPrice() Money {
+	if len(p.Variants) == 0 {
+		return p.Price
+	}
+	price := p.Variants[0].EffectivePrice(p)
+	for _, variant := range p.Variants[1:] {
+		if effective := variant.EffectivePrice(p); effective.Cmp(price) < 0 {
+			price = effective
+		}
+	}
+	return price
+}
+
+// Label names the variant for messages: the product name followed by its option
+// values, e.g. "T-Shirt (color: red, size: M)"
+func (v ProductVariant) Label(product Product) string {
+	if len(v.Options) == 0 {
+		return product.Name
+	}
+	names := make([]string, 0, len(v.Options))
+	for name := range v.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, fmt.Sprintf("%s: %v", name, v.Options[name]))
+	}
+	return fmt.Sprintf("%s (%s)", product.Name, strings.Join(values, ", "))
+}
+
+// DefaultSKU is the SKU given to the default variant of a product created without
+// variants; the migration to variants uses the same scheme
+func DefaultSKU(productID uuid.UUID) string {
+	return "P-" + strings.ToUpper(strings.ReplaceAll(productID.String(), "-", ""))
+}
+
+// EffectiveWeightGrams returns the variant's weight, falling back to the product's
+func (v ProductVariant) EffectiveWeightGrams(product Product) int {
+	if v.WeightGrams != nil {
+		return *v.WeightGrams
+	}
+	return product.WeightGrams
 }
 
 type Category struct {
 	CategoryID  uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Name        string    `gorm:"type:varchar(100);not null"`
+	Name        string    `gorm:"type:varchar(100);not null;uniqueIndex"`
 	Description string    `gorm:"type:text"`
 	Product     []Product `gorm:"foreignKey:CategoryID"`
 }
 
 type Order struct {
-	OrderID     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null"`
-	OrderDate   time.Time
-	Status      string      `gorm:"type:varchar(50);not null"`
-	TotalAmount float64     `gorm:"type:numeric;not null"`
-	User        User        `gorm:"foreignKey:UserID"`
-	Items       []OrderItem `gorm:"foreignKey:OrderID"`
+	OrderID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null"`
+	OrderDate      time.Time
+	Status         string     `gorm:"type:varchar(50);not null"`
+	Subtotal       Money      `gorm:"type:numeric;not null"`
+	DiscountAmount Money      `gorm:"type:numeric;not null"`
+	ShippingAmount Money      `gorm:"type:numeric;not null"`
+	TaxAmount      Money      `gorm:"type:numeric;not null"`
+	TotalAmount    Money      `gorm:"type:numeric;not null"`
+	CouponID       *uuid.UUID `gorm:"type:uuid"`
+	CouponCode     string     `gorm:"type:varchar(50)"`
+	ShippingMethod string     `gorm:"type:varchar(50)"`
+	// TaxRegion and TaxRate record the rate applied when the order was placed
+	TaxRegion       string               `gorm:"type:varchar(150)"`
+	TaxRate         string               `gorm:"type:numeric;not null"`
+	ShippingAddress AddressSnapshot      `gorm:"embedded;embeddedPrefix:shipping_"`
+	BillingAddress  AddressSnapshot      `gorm:"embedded;embeddedPrefix:billing_"`
+	User            User                 `gorm:"foreignKey:UserID"`
+	Items           []OrderItem          `gorm:"foreignKey:OrderID"`
+	History         []OrderStatusHistory `gorm:"foreignKey:OrderID"`
+	Payments        []Payment            `gorm:"foreignKey:OrderID"`
+}
+
+// Order statuses
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
+type OrderStatusHistory struct {
+	HistoryID  uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	OrderID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	FromStatus string    `gorm:"type:varchar(50)"`
+	ToStatus   string    `gorm:"type:varchar(50);not null"`
+	ChangedBy  uuid.UUID `gorm:"type:uuid;not null"`
+	Note       string    `gorm:"type:text"`
+	ChangedAt  time.Time
 }
 
 type OrderItem struct {
 	OrderItemID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
 	OrderID     uuid.UUID `gorm:"type:uuid;not null"`
 	ProductID   uuid.UUID `gorm:"type:uuid;not null"`
-	Quantity    int       `gorm:"not null"`
-	Price       float64   `gorm:"type:numeric;not null"`
-	Order       Order     `gorm:"foreignKey:OrderID"`
-	Product     Product   `gorm:"foreignKey:ProductID"`
+	// VariantID is cleared if the variant is deleted; SKU and Options keep what was ordered
+	VariantID *uuid.UUID `gorm:"type:uuid"`
+	SKU       string     `gorm:"type:varchar(64)"`
+	Options   JSONMap    `gorm:"type:jsonb"`
+	Quantity  int        `gorm:"not null"`
+	Price     Money      `gorm:"type:numeric;not null"`
+	// DiscountAmount is the item's share of the order's coupon discount
+	DiscountAmount Money   `gorm:"type:numeric;not null"`
+	Order          Order   `gorm:"foreignKey:OrderID"`
+	Product        Product `gorm:"foreignKey:ProductID"`
 }
 
 type ShoppingCart struct {
-	CartID    uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID    uuid.UUID  `gorm:"type:uuid;not null;unique"`
-	Items     []CartItem `gorm:"foreignKey:CartID"`
-	CreatedAt time.Time
+	CartID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;unique"`
+	// CouponCode is the code applied to the cart; it is validated again at checkout
+	CouponCode *string    `gorm:"type:varchar(50)"`
+	Items      []CartItem `gorm:"foreignKey:CartID"`
+	CreatedAt  time.Time
 }
 
+// CartItem is a line of a cart; a cart has at most one line per variant
 type CartItem struct {
-	CartItemID uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	CartID     uuid.UUID    `gorm:"type:uuid;not null"`
-	ProductID  uuid.UUID    `gorm:"type:uuid;not null"`
-	Quantity   int          `gorm:"not null"`
-	Cart       ShoppingCart `gorm:"foreignKey:CartID"`
-	Product    Product      `gorm:"foreignKey:ProductID"`
+	CartItemID uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	CartID     uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_cart_items_cart_variant"`
+	ProductID  uuid.UUID      `gorm:"type:uuid;not null"`
+	VariantID  uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_cart_items_cart_variant"`
+	Quantity   int            `gorm:"not null"`
+	Cart       ShoppingCart   `gorm:"foreignKey:CartID"`
+	Product    Product        `gorm:"foreignKey:ProductID"`
+	Variant    ProductVariant `gorm:"foreignKey:VariantID"`
 }
 
+// Payment records a charge against an order. Refunds are stored as negative
+// payments that point at the original charge through RefundOfID. An IdempotencyKey
+// is unique among the charges of an order and among its refunds.
 type Payment struct {
-	PaymentID     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	OrderID       uuid.UUID `gorm:"type:uuid;not null"`
-	Amount        float64   `gorm:"type:numeric;not null"`
-	PaymentDate   time.Time
-	PaymentMethod string `gorm:"type:varchar(50);not null"`
+	PaymentID      uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	OrderID        uuid.UUID `gorm:"type:uuid;not null;index"`
+	Amount         Money     `gorm:"type:numeric;not null"`
+	PaymentDate    time.Time
+	PaymentMethod  string     `gorm:"type:varchar(50);not null"`
+	Provider       string     `gorm:"type:varchar(50)"`
+	Reference      string     `gorm:"type:varchar(255)"`
+	IdempotencyKey *string    `gorm:"type:varchar(255)"`
+	RefundOfID     *uuid.UUID `gorm:"type:uuid;index"`
+	Status         string     `gorm:"type:varchar(20);not null;default:completed"`
+	Refunds        []Payment  `gorm:"foreignKey:RefundOfID"`
 }
 
+// Payment statuses. Charges and refunds are stored pending and sent to the provider
+// once the transaction that recorded them has committed.
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusCompleted = "completed"
+	PaymentStatusFailed    = "failed"
+)
+
 type Review struct {
 	ReviewID  uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	ProductID uuid.UUID `gorm:"type:uuid;not null"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_reviews_product_user"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_reviews_product_user"`
 	Rating    int       `gorm:"not null"`
 	Comment   string    `gorm:"type:text"`
 	User      User      `gorm:"foreignKey:UserID"`
 	Product   Product   `gorm:"foreignKey:ProductID"`
 	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
+// Session is a login on one device. Access tokens carry the session ID so that
+// revoking the session invalidates them before they expire.
 type Session struct {
-	SessionID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null"`
-	User      User      `gorm:"foreignKey:UserID"`
+	SessionID     uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID        uuid.UUID      `gorm:"type:uuid;not null;index"`
+	User          User           `gorm:"foreignKey:UserID"`
+	UserAgent     string         `gorm:"type:varchar(255)"`
+	IPAddress     string         `gorm:"type:varchar(45)"`
+	RefreshTokens []RefreshToken `gorm:"foreignKey:SessionID"`
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	RevokedAt     *time.Time
+}
+
+// RefreshToken is one link in a session's chain of rotating refresh tokens.
+// Only a hash of the token is stored; presenting a used token revokes the session.
+type RefreshToken struct {
+	TokenID   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
 	CreatedAt time.Time
-	ExpiresAt time.Time
+	UsedAt    *time.Time
 }
 
+// Role names
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type Role struct {
-	RoleID   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	RoleName string    `gorm:"type:varchar(50);not null"`
+	RoleID      uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	RoleName    string       `gorm:"type:varchar(50);not null;uniqueIndex"`
+	Permissions []Permission `gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionID"`
+}
+
+// Permission names. Routes require permissions rather than roles, so new roles
+// can be composed from existing capabilities.
+const (
+	PermissionProductWrite    = "product:write"
+	PermissionCategoryWrite   = "category:write"
+	PermissionOrderManage     = "order:manage"
+	PermissionOrderRefund     = "order:refund"
+	PermissionUserManage      = "user:manage"
+	PermissionRoleManage      = "role:manage"
+	PermissionReportRead      = "report:read"
+	PermissionAuditRead       = "audit:read"
+	PermissionInventoryManage = "inventory:manage"
+	PermissionPromotionManage = "promotion:manage"
+	PermissionPricingManage   = "pricing:manage"
+)
+
+// Permission is a capability that can be granted to roles
+type Permission struct {
+	PermissionID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name         string    `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Description  string    `gorm:"type:text"`
 }
 
 type UserAddress struct {
-	AddressID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null,unique"`
-	Street    string    `gorm:"type:varchar(255)"`
-	City      string    `gorm:"type:varchar(100)"`
-	State     string    `gorm:"type:varchar(100)"`
-	ZipCode   string    `gorm:"type:varchar(20)"`
+	AddressID         uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID            uuid.UUID `gorm:"type:uuid;not null;index"`
+	Label             string    `gorm:"type:varchar(50)"`
+	RecipientName     string    `gorm:"type:varchar(100)"`
+	Street            string    `gorm:"type:varchar(255)"`
+	City              string    `gorm:"type:varchar(100)"`
+	State             string    `gorm:"type:varchar(100)"`
+	ZipCode           string    `gorm:"type:varchar(20)"`
+	Country           string    `gorm:"type:varchar(2)"`
+	IsDefaultShipping bool      `gorm:"not null;default:false"`
+	IsDefaultBilling  bool      `gorm:"not null;default:false"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// AddressSnapshot is a copy of a UserAddress stored on an order, so later
+// edits to the address book do not change past orders.
+type AddressSnapshot struct {
+	RecipientName string `gorm:"type:varchar(100)"`
+	Street        string `gorm:"type:varchar(255)"`
+	City          string `gorm:"type:varchar(100)"`
+	State         string `gorm:"type:varchar(100)"`
+	ZipCode       string `gorm:"type:varchar(20)"`
+	Country       string `gorm:"type:varchar(2)"`
 }
 
 type ProductImage struct {
-	ImageID   uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	ProductID uuid.UUID `gorm:"type:uuid;not null"`
-	ImageURL  string    `gorm:"type:text;not null"`
-	Product   Product   `gorm:"foreignKey:ProductID"`
-	CreatedAt time.Time
+	ImageID      uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ProductID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	ImageURL     string    `gorm:"type:text;not null"`
+	ThumbnailURL string    `gorm:"type:text"`
+	StorageKey   string    `gorm:"type:varchar(255)"`
+	ThumbnailKey string    `gorm:"type:varchar(255)"`
+	ContentType  string    `gorm:"type:varchar(50)"`
+	Size         int64
+	Position     int     `gorm:"not null;default:0"`
+	Product      Product `gorm:"foreignKey:ProductID"`
+	CreatedAt    time.Time
 }
 
+// AuditLog records a mutating request: who did it, what it changed and where it came from.
+// Before and After hold only the fields that changed.
 type AuditLog struct {
-	LogID     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	Action    string    `gorm:"type:text"`
-	UserID    uuid.UUID `gorm:"type:uuid"`
-	User      User      `gorm:"foreignKey:UserID"`
-	Timestamp time.Time
+	LogID      uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Action     string     `gorm:"type:text"`
+	UserID     *uuid.UUID `gorm:"type:uuid;index"`
+	User       *User      `gorm:"foreignKey:UserID" json:",omitempty"`
+	EntityType string     `gorm:"type:varchar(50);index:idx_audit_logs_entity"`
+	EntityID   string     `gorm:"type:varchar(64);index:idx_audit_logs_entity"`
+	Before     JSONMap    `gorm:"type:jsonb"`
+	After      JSONMap    `gorm:"type:jsonb"`
+	Method     string     `gorm:"type:varchar(10)"`
+	Path       string     `gorm:"type:text"`
+	StatusCode int
+	IPAddress  string    `gorm:"type:varchar(45)"`
+	RequestID  string    `gorm:"type:varchar(64)"`
+	Timestamp  time.Time `gorm:"index"`
 }
 
 type Cache struct {
-	CacheKey       string `gorm:"type:varchar(255);primaryKey"`
-	CacheValue     string `gorm:"type:text"`
-	ExpirationTime time.Time
-}
-
-func Migrate(db *gorm.DB) {
-	db.AutoMigrate(
-		&User{},
-		&Product{},
-		&Category{},
-		&Order{},
-		&OrderItem{},
-		&ShoppingCart{},
-		&CartItem{},
-		&Payment{},
-		&Review{},
-		&Session{},
-		&Role{},
-		&UserAddress{},
-		&ProductImage{},
-		&AuditLog{},
-		&Cache{},
-	)
+	CacheKey       string    `gorm:"type:varchar(255);primaryKey"`
+	CacheValue     string    `gorm:"type:text"`
+	ExpirationTime time.Time `gorm:"index"`
+}
+
+// CacheTag associates a cache entry with a tag so related entries can be invalidated together
+type CacheTag struct {
+	Tag      string `gorm:"type:varchar(255);primaryKey"`
+	CacheKey string `gorm:"type:varchar(255);primaryKey;index"`
+}
+
+// Stock movement reasons. Adjustments made by staff use restock, damage or
+// correction; the others are recorded by checkout, cancellation and reservations.
+const (
+	StockReasonRestock      = "restock"
+	StockReasonDamage       = "damage"
+	StockReasonCorrection   = "correction"
+	StockReasonSale         = "sale"
+	StockReasonCancellation = "cancellation"
+	StockReasonReservation  = "reservation"
+	StockReasonRelease      = "reservation_release"
+)
+
+// StockMovement is one entry in the inventory ledger. Quantity is the signed change
+// and StockAfter the variant's stock once it was applied.
+type StockMovement struct {
+	MovementID uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ProductID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	VariantID  uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Quantity   int        `gorm:"not null"`
+	StockAfter int        `gorm:"not null"`
+	Reason     string     `gorm:"type:varchar(30);not null"`
+	Note       string     `gorm:"type:text"`
+	OrderID    *uuid.UUID `gorm:"type:uuid;index"`
+	ActorID    *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt  time.Time
+}
+
+// StockReservation holds stock for a user between starting and completing checkout.
+// The quantity is taken out of the variant's stock and returned when the reservation
+// is released or expires.
+type StockReservation struct {
+	ReservationID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index"`
+	ProductID     uuid.UUID `gorm:"type:uuid;not null"`
+	VariantID     uuid.UUID `gorm:"type:uuid;not null"`
+	Quantity      int       `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"index"`
+	CreatedAt     time.Time
+}
+
+// Notification types
+const (
+	NotificationLowStock    = "low_stock"
+	NotificationBackInStock = "back_in_stock"
+	NotificationPriceDrop   = "price_drop"
+)
+
+// Notification is an in-app message for a user
+type Notification struct {
+	NotificationID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	Type           string    `gorm:"type:varchar(50);not null"`
+	Title          string    `gorm:"type:varchar(255);not null"`
+	Message        string    `gorm:"type:text"`
+	Data           JSONMap   `gorm:"type:jsonb"`
+	ReadAt         *time.Time
+	CreatedAt      time.Time
+}
+
+// Coupon discount types
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+)
+
+// Coupon is a discount code. A coupon scoped to products or categories only
+// discounts the matching cart lines; an unscoped coupon discounts the whole cart.
+type Coupon struct {
+	CouponID     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Code         string    `gorm:"type:varchar(50);not null;uniqueIndex"`
+	Description  string    `gorm:"type:text"`
+	DiscountType string    `gorm:"type:varchar(20);not null"`
+	// PercentOff is used by percentage coupons, AmountOff by fixed ones
+	PercentOff    int   `gorm:"not null"`
+	AmountOff     Money `gorm:"type:numeric;not null"`
+	MinOrderValue Money `gorm:"type:numeric;not null"`
+	// UsageLimit and PerUserLimit are unlimited when nil
+	UsageLimit   *int
+	PerUserLimit *int
+	UsageCount   int `gorm:"not null"`
+	StartsAt     *time.Time
+	EndsAt       *time.Time
+	Active       bool       `gorm:"not null"`
+	Products     []Product  `gorm:"many2many:coupon_products;joinForeignKey:CouponID;joinReferences:ProductID"`
+	Categories   []Category `gorm:"many2many:coupon_categories;joinForeignKey:CouponID;joinReferences:CategoryID"`
+	CreatedAt    time.Time
+}
+
+// CouponRedemption records a coupon used by an order
+type CouponRedemption struct {
+	RedemptionID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	CouponID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	OrderID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Discount     Money     `gorm:"type:numeric;not null"`
+	CreatedAt    time.Time
+}
+
+// TaxRate is the sales tax rate of a region. An empty State or ZipPrefix matches
+// any value; the most specific matching rate applies.
+type TaxRate struct {
+	TaxRateID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name      string    `gorm:"type:varchar(100);not null"`
+	Country   string    `gorm:"type:varchar(2);not null"`
+	State     string    `gorm:"type:varchar(100);not null"`
+	ZipPrefix string    `gorm:"type:varchar(20);not null"`
+	// Rate is a decimal fraction, e.g. "0.0725" for 7.25%
+	Rate string `gorm:"type:numeric;not null"`
+	// TaxShipping makes shipping costs taxable in the region
+	TaxShipping bool `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+// Shipping method bases
+const (
+	ShippingByWeight = "weight"
+	ShippingByPrice  = "price"
+)
+
+// ShippingMethod prices shipping by the cart's weight or value. The tier with the
+// highest minimum the cart reaches applies; a cart below every tier cannot use the method.
+type ShippingMethod struct {
+	ShippingMethodID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Code             string    `gorm:"type:varchar(50);not null;uniqueIndex"`
+	Name             string    `gorm:"type:varchar(100);not null"`
+	Basis            string    `gorm:"type:varchar(20);not null"`
+	// FreeThreshold makes shipping free once the discounted subtotal reaches it
+	FreeThreshold *Money         `gorm:"type:numeric"`
+	Active        bool           `gorm:"not null"`
+	Tiers         []ShippingTier `gorm:"foreignKey:ShippingMethodID"`
+	CreatedAt     time.Time
+}
+
+// ShippingTier is one step of a shipping method's price table. MinWeightGrams is
+// used by weight-based methods and MinSubtotal by price-based ones.
+type ShippingTier struct {
+	TierID           uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ShippingMethodID uuid.UUID `gorm:"type:uuid;not null;index"`
+	MinWeightGrams   int       `gorm:"not null"`
+	MinSubtotal      Money     `gorm:"type:numeric;not null"`
+	Cost             Money     `gorm:"type:numeric;not null"`
+}
+
+// Wishlist is a named list of products a user wants to keep track of. Anyone with
+// the ShareToken can view a shared wishlist; it is nil while the list is private.
+type Wishlist struct {
+	WishlistID uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID     uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_wishlists_user_name"`
+	Name       string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_wishlists_user_name"`
+	ShareToken *string        `gorm:"type:varchar(64);uniqueIndex"`
+	Items      []WishlistItem `gorm:"foreignKey:WishlistID"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// WishlistItem is a product on a wishlist. VariantID pins one variant; without it
+// the user wants the product in any variant.
+type WishlistItem struct {
+	WishlistItemID uuid.UUID       `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	WishlistID     uuid.UUID       `gorm:"type:uuid;not null;index"`
+	ProductID      uuid.UUID       `gorm:"type:uuid;not null;index"`
+	VariantID      *uuid.UUID      `gorm:"type:uuid"`
+	Product        Product         `gorm:"foreignKey:ProductID"`
+	Variant        *ProductVariant `gorm:"foreignKey:VariantID"`
+	CreatedAt      time.Time
 }
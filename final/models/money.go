@@ -0,0 +1,273 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Currency is the ISO 4217 code the store trades in, from CURRENCY. Amounts are
+// stored without a currency column, so every Money read from the database is in it.
+var Currency = "USD"
+
+// currencyExponents lists the currencies whose minor unit is not a hundredth
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "PYG": 0, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// currencyPattern matches an ISO 4217 currency code
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// decimalPattern matches the plain decimal notation accepted for amounts
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// InitCurrency reads the store currency from the CURRENCY environment variable
+func InitCurrency() error {
+	if code := os.Getenv("CURRENCY"); code != "" {
+		if !currencyPattern.MatchString(code) {
+			return fmt.Errorf("invalid CURRENCY %q", code)
+		}
+		Currency = code
+	}
+	return nil
+}
+
+// currencyExponent returns the number of decimal places of a currency's minor unit
+func currencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Money is an exact amount in a currency's minor unit, e.g. cents. It is stored in
+// numeric columns as a decimal and serialized in the API as
+// {"amount": "12.34", "currency": "USD"}.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// NewMoney returns an amount of minor units in the store currency
+func NewMoney(minor int64) Money {
+	return Money{Minor: minor, Currency: Currency}
+}
+
+// ParseMoney parses a decimal amount in the store currency. Amounts with more
+// decimal places than the currency's minor unit are rejected rather than rounded.
+func ParseMoney(s string) (Money, error) {
+	if !decimalPattern.MatchString(s) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	minor := rat.Mul(rat, minorUnitScale(Currency))
+	if !minor.IsInt() {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", s, currencyExponent(Currency))
+	}
+	if !minor.Num().IsInt64() {
+		return Money{}, fmt.Errorf("amount %q is out of range", s)
+	}
+	return NewMoney(minor.Num().Int64()), nil
+}
+
+// minorUnitScale returns 10^exponent of the currency
+func minorUnitScale(currency string) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currencyExponent(currency))), nil)
+	return new(big.Rat).SetInt(scale)
+}
+
+// roundMinor rounds a rational number of minor units half away from zero. This is
+// the only rounding rule applied to money.
+func roundMinor(minor *big.Rat) int64 {
+	num, den := minor.Num(), minor.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	// |remainder| * 2 >= denominator rounds away from zero
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient.Int64()
+}
+
+// Add returns m + other; both must be in the same currency
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Minor: m.Minor + other.Minor, Currency: m.currency()}
+}
+
+// Sub returns m - other; both must be in the same currency
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Minor: m.Minor - other.Minor, Currency: m.currency()}
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Minor: m.Minor * int64(quantity), Currency: m.currency()}
+}
+
+// MulRate returns m multiplied by a rate, such as a discount or tax rate, rounded
+// half away from zero to the minor unit. Amounts derived from rates should be
+// computed once, at checkout, and stored rather than recomputed.
+func (m Money) MulRate(rate *big.Rat) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Minor), rate)
+	return Money{Minor: roundMinor(product), Currency: m.currency()}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.currency()}
+}
+
+// Cmp compares m with other, returning -1, 0 or +1; both must be in the same currency
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Minor < other.Minor:
+		return -1
+	case m.Minor > other.Minor:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// IsPositive reports whether the amount is greater than zero
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+// IsNegative reports whether the amount is less than zero
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// mustMatch panics when two amounts are in different currencies. Amounts only come
+// from the store currency, so a mismatch is a programming error.
+func (m Money) mustMatch(other Money) {
+	if m.currency() != other.currency() {
+		panic(fmt.Sprintf("money: currency mismatch %s and %s", m.currency(), other.currency()))
+	}
+}
+
+// currency returns the amount's currency; the zero Money is in the store currency
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return Currency
+	}
+	return m.Currency
+}
+
+// String formats the amount as a decimal with the currency's number of decimal places
+func (m Money) String() string {
+	exponent := currencyExponent(m.currency())
+	sign, minor := "", m.Minor
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// moneyJSON is the API representation of Money
+type moneyJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.String(), Currency: m.currency()})
+}
+
+// UnmarshalJSON accepts {"amount": "12.34", "currency": "USD"}, a decimal string
+// or a JSON number. Amounts must be in the store currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	var amount, currency string
+	switch {
+	case len(data) > 0 && data[0] == '{':
+		var input moneyJSON
+		if err := json.Unmarshal(data, &input); err != nil {
+			return err
+		}
+		amount, currency = input.Amount, input.Currency
+	case len(data) > 0 && data[0] == '"':
+		if err := json.Unmarshal(data, &amount); err != nil {
+			return err
+		}
+	default:
+		// Numbers are parsed from their literal text, never through float64
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("invalid amount %s", data)
+		}
+		amount = number.String()
+	}
+	if currency != "" && currency != Currency {
+		return fmt.Errorf("unsupported currency %q, amounts must be in %s", currency, Currency)
+	}
+
+	money, err := ParseMoney(amount)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+// Value stores the amount as a decimal
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a numeric column in the store currency. Values with more decimal
+// places than the currency allows, such as averages, are rounded.
+func (m *Money) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		*m = NewMoney(0)
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return fmt.Errorf("invalid amount %q", s)
+	}
+	*m = NewMoney(roundMinor(rat.Mul(rat, minorUnitScale(Currency))))
+	return nil
+}
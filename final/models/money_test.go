@@ -0,0 +1,175 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+// useCurrency switches the store currency for the duration of a test
+func useCurrency(t *testing.T, code string) {
+	previous := Currency
+	Currency = code
+	t.Cleanup(func() { Currency = previous })
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := map[string]int64{
+		"0":       0,
+		"12":      1200,
+		"12.3":    1230,
+		"12.34":   1234,
+		"-0.05":   -5,
+		"0012.00": 1200,
+	}
+	for input, want := range tests {
+		got, err := ParseMoney(input)
+		if err != nil {
+			t.Errorf("ParseMoney(%q) failed: %v", input, err)
+		} else if got.Minor != want || got.Currency != Currency {
+			t.Errorf("ParseMoney(%q) = %+v, want %d minor units", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "12.345", "1e3", "12.", ".5", "+1", "1,000", "99999999999999999999"} {
+		if _, err := ParseMoney(input); err == nil {
+			t.Errorf("ParseMoney(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestParseMoneyUsesCurrencyExponent(t *testing.T) {
+	useCurrency(t, "JPY")
+	if got, err := ParseMoney("1500"); err != nil || got.Minor != 1500 {
+		t.Errorf("ParseMoney(1500) = %+v, %v, want 1500 yen", got, err)
+	}
+	if _, err := ParseMoney("1500.5"); err == nil {
+		t.Error("yen with decimal places was accepted")
+	}
+
+	useCurrency(t, "KWD")
+	if got, err := ParseMoney("1.234"); err != nil || got.Minor != 1234 {
+		t.Errorf("ParseMoney(1.234) = %+v, %v, want 1234 fils", got, err)
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{Minor: 1234, Currency: "USD"}, "12.34"},
+		{Money{Minor: 5, Currency: "USD"}, "0.05"},
+		{Money{Minor: 0, Currency: "USD"}, "0.00"},
+		{Money{Minor: -1205, Currency: "USD"}, "-12.05"},
+		{Money{Minor: 1500, Currency: "JPY"}, "1500"},
+		{Money{Minor: 1234, Currency: "KWD"}, "1.234"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, b := NewMoney(1050), NewMoney(250)
+	if got := a.Add(b); got.Minor != 1300 {
+		t.Errorf("Add = %d, want 1300", got.Minor)
+	}
+	if got := b.Sub(a); got.Minor != -800 || !got.IsNegative() {
+		t.Errorf("Sub = %d, want -800", got.Minor)
+	}
+	if got := a.Mul(3); got.Minor != 3150 {
+		t.Errorf("Mul = %d, want 3150", got.Minor)
+	}
+	if a.Cmp(b) != 1 || b.Cmp(a) != -1 || a.Cmp(a) != 0 {
+		t.Error("Cmp does not order the amounts")
+	}
+	// The zero Money is in the store currency
+	if got := (Money{}).Add(a); got.Minor != 1050 || got.Currency != Currency {
+		t.Errorf("zero + a = %+v", got)
+	}
+}
+
+func TestMoneyPanicsOnCurrencyMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("adding different currencies did not panic")
+		}
+	}()
+	Money{Minor: 1, Currency: "USD"}.Add(Money{Minor: 1, Currency: "EUR"})
+}
+
+func TestMulRateRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		minor int64
+		rate  string
+		want  int64
+	}{
+		{1000, "0.15", 150},
+		{999, "0.15", 150},  // 149.85
+		{1001, "0.15", 150}, // 150.15
+		{10, "0.25", 3},     // 2.5
+		{-10, "0.25", -3},   // -2.5
+		{30, "1/3", 10},
+		{1, "1/3", 0},        // 0.33
+		{-999, "0.15", -150}, // -149.85
+	}
+	for _, tt := range tests {
+		rate, _ := new(big.Rat).SetString(tt.rate)
+		if got := NewMoney(tt.minor).MulRate(rate); got.Minor != tt.want {
+			t.Errorf("%d * %s = %d, want %d", tt.minor, tt.rate, got.Minor, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(Money{Minor: 1234, Currency: "USD"})
+	if err != nil || string(data) != `{"amount":"12.34","currency":"USD"}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+
+	useCurrency(t, "USD")
+	for _, input := range []string{`{"amount":"12.34","currency":"USD"}`, `{"amount":"12.34"}`, `"12.34"`, `12.34`} {
+		var m Money
+		if err := json.Unmarshal([]byte(input), &m); err != nil || m.Minor != 1234 {
+			t.Errorf("Unmarshal(%s) = %+v, %v, want 1234", input, m, err)
+		}
+	}
+	for _, input := range []string{`{"amount":"12.34","currency":"EUR"}`, `12.345`, `1e2`, `true`} {
+		var m Money
+		if err := json.Unmarshal([]byte(input), &m); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  int64
+	}{
+		{nil, 0},
+		{"12.34", 1234},
+		{[]byte("12.3400"), 1234},
+		{"12.345", 1235},
+		{"-12.345", -1235},
+		{int64(7), 700},
+		{float64(0.1), 10},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.value); err != nil || m.Minor != tt.want {
+			t.Errorf("Scan(%v) = %d, %v, want %d", tt.value, m.Minor, err, tt.want)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Error("scanning a bool succeeded")
+	}
+	if value, _ := NewMoney(-5).Value(); value != "-0.05" {
+		t.Errorf("Value = %v, want -0.05", value)
+	}
+}
@@ -0,0 +1,51 @@
+// Package notifications stores in-app notifications for users. Notifications are
+// written in the caller's transaction so they only appear when the change that
+// caused them is committed.
+package notifications
+
+import (
+	"final/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Message is the content of a notification
+type Message struct {
+	Type    string
+	Title   string
+	Message string
+	// Data carries identifiers a client needs to act on the notification
+	Data models.JSONMap
+}
+
+// Notify sends a notification to each of the users
+func Notify(tx *gorm.DB, msg Message, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rows := make([]models.Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		rows = append(rows, models.Notification{
+			UserID:  userID,
+			Type:    msg.Type,
+			Title:   msg.Title,
+			Message: msg.Message,
+			Data:    msg.Data,
+		})
+	}
+	return tx.Create(&rows).Error
+}
+
+// NotifyPermission sends a notification to every user whose role grants the permission
+func NotifyPermission(tx *gorm.DB, permission string, msg Message) error {
+	var userIDs []uuid.UUID
+	if err := tx.Model(&models.User{}).
+		Joins("JOIN role_permissions ON role_permissions.role_id = users.role_id").
+		Joins("JOIN permissions ON permissions.permission_id = role_permissions.permission_id").
+		Where("permissions.name = ?", permission).
+		Pluck("users.user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	return Notify(tx, msg, userIDs...)
+}
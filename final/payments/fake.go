@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// FakeDeclineMethod makes the fake provider decline a charge, for exercising failure paths
+const FakeDeclineMethod = "fake_decline"
+
+// FakeProvider is an offline provider for development and tests.
+// It accepts every charge except FakeDeclineMethod and replays results for repeated idempotency keys.
+type FakeProvider struct {
+	mu      sync.Mutex
+	results map[string]Result
+}
+
+// NewFakeProvider creates an empty fake provider
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{results: make(map[string]Result)}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Charge(ctx context.Context, req ChargeRequest) (Result, error) {
+	if req.Method == FakeDeclineMethod {
+		return Result{}, ErrDeclined
+	}
+	if !req.Amount.IsPositive() {
+		return Result{}, fmt.Errorf("%w: amount must be positive", ErrDeclined)
+	}
+	return p.record("ch", req.IdempotencyKey), nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (Result, error) {
+	if req.Reference == "" || !req.Amount.IsPositive() {
+		return Result{}, fmt.Errorf("%w: invalid refund", ErrDeclined)
+	}
+	return p.record("re", req.IdempotencyKey), nil
+}
+
+// record returns the stored result for a key, or a new reference
+func (p *FakeProvider) record(prefix, key string) Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key != "" {
+		if result, ok := p.results[key]; ok {
+			return result
+		}
+	}
+	result := Result{Reference: fmt.Sprintf("fake_%s_%s", prefix, uuid.NewString())}
+	if key != "" {
+		p.results[key] = result
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"final/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrDeclined is returned by a provider when it refuses a charge or refund
+var ErrDeclined = errors.New("payment declined")
+
+// ChargeRequest describes a charge against an order
+type ChargeRequest struct {
+	OrderID        uuid.UUID
+	Amount         models.Money
+	Method         string
+	IdempotencyKey string
+}
+
+// RefundRequest describes a refund of a previous charge
+type RefundRequest struct {
+	Reference      string
+	Amount         models.Money
+	IdempotencyKey string
+}
+
+// Result is what a provider returns for a successful charge or refund
+type Result struct {
+	Reference string
+}
+
+// PaymentProvider is implemented by every payment backend
+type PaymentProvider interface {
+	// Name identifies the provider on stored payments
+	Name() string
+	// Charge collects the requested amount
+	Charge(ctx context.Context, req ChargeRequest) (Result, error)
+	// Refund returns part or all of a previous charge identified by its reference
+	Refund(ctx context.Context, req RefundRequest) (Result, error)
+}
+
+// Provider is the payment backend used by the API; it defaults to the offline fake
+var Provider PaymentProvider = NewFakeProvider()
@@ -0,0 +1,209 @@
+// Package pricing turns a cart into a priced quote: item subtotal, discount,
+// shipping and tax. Tax and shipping come from pluggable calculators selected
+// with TAX_CALCULATOR and SHIPPING_CALCULATOR.
+package pricing
+
+import (
+	"errors"
+	"final/models"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrShippingUnavailable is returned when no shipping method, or not the requested
+// one, can ship the cart
+var ErrShippingUnavailable = errors.New("shipping method unavailable")
+
+// Quote line types
+const (
+	LineItem     = "item"
+	LineDiscount = "discount"
+	LineShipping = "shipping"
+	LineTax      = "tax"
+)
+
+// Line is one product line of the cart being priced
+type Line struct {
+	ProductID   uuid.UUID
+	Name        string
+	Quantity    int
+	Price       models.Money
+	WeightGrams int
+}
+
+// Request describes the cart to price
+type Request struct {
+	Lines []Line
+	// Discount has already been computed from the cart's coupon
+	Discount models.Money
+	Address  models.AddressSnapshot
+	// ShippingMethod is the code of the chosen method; empty picks the cheapest
+	ShippingMethod string
+}
+
+// Subtotal returns the sum of the line totals
+func (r Request) Subtotal() models.Money {
+	subtotal := models.NewMoney(0)
+	for _, line := range r.Lines {
+		subtotal = subtotal.Add(line.Price.Mul(line.Quantity))
+	}
+	return subtotal
+}
+
+// WeightGrams returns the total shipping weight
+func (r Request) WeightGrams() int {
+	weight := 0
+	for _, line := range r.Lines {
+		weight += line.WeightGrams * line.Quantity
+	}
+	return weight
+}
+
+// ShippingOption is a shipping method that can ship the cart, with its cost
+type ShippingOption struct {
+	Code string
+	Name string
+	Cost models.Money
+}
+
+// TaxResult is the tax owed on a cart
+type TaxResult struct {
+	// Region names the rate that applied; empty when no rate matched
+	Region string
+	// Rate is a decimal fraction
+	Rate   string
+	Amount models.Money
+}
+
+// QuoteLine is one line of a quote's breakdown
+type QuoteLine struct {
+	Type        string
+	Description string
+	Quantity    int `json:",omitempty"`
+	Amount      models.Money
+}
+
+// Quote is the priced cart
+type Quote struct {
+	Lines           []QuoteLine
+	Subtotal        models.Money
+	Discount        models.Money
+	Shipping        models.Money
+	Tax             models.Money
+	Total           models.Money
+	ShippingMethod  string
+	ShippingOptions []ShippingOption
+	TaxRegion       string
+	TaxRate         string
+}
+
+// TaxCalculator computes the tax owed on a cart
+type TaxCalculator interface {
+	// Tax returns the tax on the discounted subtotal and, where the region taxes it,
+	// the shipping cost
+	Tax(db *gorm.DB, req Request, shipping models.Money) (TaxResult, error)
+}
+
+// ShippingCalculator prices shipping for a cart
+type ShippingCalculator interface {
+	// Options returns the methods that can ship the cart, cheapest first
+	Options(db *gorm.DB, req Request) ([]ShippingOption, error)
+}
+
+// Tax is the tax calculator used at checkout
+var Tax TaxCalculator = RegionTax{}
+
+// Shipping is the shipping calculator used at checkout
+var Shipping ShippingCalculator = TieredShipping{}
+
+// Init selects the calculators from TAX_CALCULATOR ("region" or "none") and
+// SHIPPING_CALCULATOR ("tiered" or "free")
+func Init() error {
+	switch name := os.Getenv("TAX_CALCULATOR"); name {
+	case "", "region":
+		Tax = RegionTax{}
+	case "none":
+		Tax = NoTax{}
+	default:
+		return fmt.Errorf("unknown tax calculator %q", name)
+	}
+
+	switch name := os.Getenv("SHIPPING_CALCULATOR"); name {
+	case "", "tiered":
+		Shipping = TieredShipping{}
+	case "free":
+		Shipping = FreeShipping{}
+	default:
+		return fmt.Errorf("unknown shipping calculator %q", name)
+	}
+	return nil
+}
+
+// Calculate prices the cart with the configured calculators. Shipping and tax are
+// rounded by the calculators once, here, and the results are stored on the order.
+func Calculate(db *gorm.DB, req Request) (Quote, error) {
+	quote := Quote{
+		Subtotal: req.Subtotal(),
+		Discount: req.Discount,
+	}
+	if quote.Discount.Currency == "" {
+		quote.Discount = models.NewMoney(0)
+	}
+	for _, line := range req.Lines {
+		quote.Lines = append(quote.Lines, QuoteLine{
+			Type:        LineItem,
+			Description: line.Name,
+			Quantity:    line.Quantity,
+			Amount:      line.Price.Mul(line.Quantity),
+		})
+	}
+	if !quote.Discount.IsZero() {
+		quote.Lines = append(quote.Lines, QuoteLine{Type: LineDiscount, Description: "Discount", Amount: quote.Discount.Neg()})
+	}
+
+	options, err := Shipping.Options(db, req)
+	if err != nil {
+		return quote, err
+	}
+	quote.ShippingOptions = options
+	option, err := chooseShipping(options, req.ShippingMethod)
+	if err != nil {
+		return quote, err
+	}
+	quote.ShippingMethod = option.Code
+	quote.Shipping = option.Cost
+	quote.Lines = append(quote.Lines, QuoteLine{Type: LineShipping, Description: option.Name, Amount: option.Cost})
+
+	tax, err := Tax.Tax(db, req, option.Cost)
+	if err != nil {
+		return quote, err
+	}
+	quote.Tax = tax.Amount
+	quote.TaxRegion = tax.Region
+	quote.TaxRate = tax.Rate
+	if tax.Region != "" {
+		quote.Lines = append(quote.Lines, QuoteLine{Type: LineTax, Description: tax.Region, Amount: tax.Amount})
+	}
+
+	quote.Total = quote.Subtotal.Sub(quote.Discount).Add(quote.Shipping).Add(quote.Tax)
+	return quote, nil
+}
+
+// chooseShipping returns the requested option, or the cheapest when none was requested
+func chooseShipping(options []ShippingOption, code string) (ShippingOption, error) {
+	if len(options) == 0 {
+		return ShippingOption{}, fmt.Errorf("%w: no shipping method can ship this cart", ErrShippingUnavailable)
+	}
+	if code == "" {
+		return options[0], nil
+	}
+	for _, option := range options {
+		if option.Code == code {
+			return option, nil
+		}
+	}
+	return ShippingOption{}, fmt.Errorf("%w: %q cannot ship this cart", ErrShippingUnavailable, code)
+}
@@ -0,0 +1,201 @@
+package pricing
+
+import (
+	"errors"
+	"final/models"
+	"math/big"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fixedShipping offers the same options for every cart
+type fixedShipping []ShippingOption
+
+func (s fixedShipping) Options(db *gorm.DB, req Request) ([]ShippingOption, error) {
+	return s, nil
+}
+
+// flatTax taxes the discounted subtotal and shipping at one rate
+type flatTax struct{ rate string }
+
+func (t flatTax) Tax(db *gorm.DB, req Request, shipping models.Money) (TaxResult, error) {
+	fraction, _ := new(big.Rat).SetString(t.rate)
+	taxable := req.Subtotal().Sub(req.Discount).Add(shipping)
+	return TaxResult{Region: "Flat", Rate: t.rate, Amount: taxable.MulRate(fraction)}, nil
+}
+
+// useCalculators swaps the package calculators for the duration of a test
+func useCalculators(t *testing.T, tax TaxCalculator, shipping ShippingCalculator) {
+	previousTax, previousShipping := Tax, Shipping
+	Tax, Shipping = tax, shipping
+	t.Cleanup(func() { Tax, Shipping = previousTax, previousShipping })
+}
+
+func TestRequestTotals(t *testing.T) {
+	req := Request{Lines: []Line{
+		{Quantity: 2, Price: models.NewMoney(1050), WeightGrams: 300},
+		{Quantity: 1, Price: models.NewMoney(499), WeightGrams: 1200},
+	}}
+	if got := req.Subtotal(); got.Cmp(models.NewMoney(2599)) != 0 {
+		t.Errorf("Subtotal = %s, want 25.99", got)
+	}
+	if got := req.WeightGrams(); got != 1800 {
+		t.Errorf("WeightGrams = %d, want 1800", got)
+	}
+}
+
+func TestTierCost(t *testing.T) {
+	byWeight := models.ShippingMethod{Basis: models.ShippingByWeight, Tiers: []models.ShippingTier{
+		{MinWeightGrams: 1000, Cost: models.NewMoney(900)},
+		{MinWeightGrams: 0, Cost: models.NewMoney(500)},
+		{MinWeightGrams: 5000, Cost: models.NewMoney(2000)},
+	}}
+	byPrice := models.ShippingMethod{Basis: models.ShippingByPrice, Tiers: []models.ShippingTier{
+		{MinSubtotal: models.NewMoney(1000), Cost: models.NewMoney(700)},
+		{MinSubtotal: models.NewMoney(5000), Cost: models.NewMoney(300)},
+	}}
+
+	tests := []struct {
+		name     string
+		method   models.ShippingMethod
+		subtotal models.Money
+		weight   int
+		want     models.Money
+		ok       bool
+	}{
+		{"lightest tier", byWeight, models.NewMoney(0), 0, models.NewMoney(500), true},
+		{"middle weight tier", byWeight, models.NewMoney(0), 4999, models.NewMoney(900), true},
+		{"exact weight minimum", byWeight, models.NewMoney(0), 5000, models.NewMoney(2000), true},
+		{"below every price tier", byPrice, models.NewMoney(999), 0, models.Money{}, false},
+		{"lowest price tier", byPrice, models.NewMoney(1000), 0, models.NewMoney(700), true},
+		{"highest price tier", byPrice, models.NewMoney(7500), 0, models.NewMoney(300), true},
+		{"unknown basis", models.ShippingMethod{Basis: "volume", Tiers: byWeight.Tiers}, models.NewMoney(0), 0, models.Money{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tierCost(tt.method, tt.subtotal, tt.weight)
+		if ok != tt.ok || (ok && got.Cmp(tt.want) != 0) {
+			t.Errorf("%s: tierCost = %s, %v, want %s, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestChooseShipping(t *testing.T) {
+	options := []ShippingOption{
+		{Code: "standard", Cost: models.NewMoney(500)},
+		{Code: "express", Cost: models.NewMoney(1500)},
+	}
+	if got, err := chooseShipping(options, ""); err != nil || got.Code != "standard" {
+		t.Errorf("default = %v, %v, want standard", got.Code, err)
+	}
+	if got, err := chooseShipping(options, "express"); err != nil || got.Code != "express" {
+		t.Errorf("express = %v, %v, want express", got.Code, err)
+	}
+	if _, err := chooseShipping(options, "overnight"); !errors.Is(err, ErrShippingUnavailable) {
+		t.Errorf("unknown method error = %v, want ErrShippingUnavailable", err)
+	}
+	if _, err := chooseShipping(nil, ""); !errors.Is(err, ErrShippingUnavailable) {
+		t.Errorf("no options error = %v, want ErrShippingUnavailable", err)
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	useCalculators(t, flatTax{rate: "0.1"}, fixedShipping{
+		{Code: "standard", Name: "Standard", Cost: models.NewMoney(500)},
+		{Code: "express", Name: "Express", Cost: models.NewMoney(1500)},
+	})
+
+	quote, err := Calculate(nil, Request{
+		Lines:          []Line{{Name: "Lamp", Quantity: 2, Price: models.NewMoney(2000)}},
+		Discount:       models.NewMoney(1000),
+		ShippingMethod: "express",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (40.00 - 10.00 + 15.00) taxed at 10%
+	want := map[string]models.Money{
+		"subtotal": models.NewMoney(4000),
+		"discount": models.NewMoney(1000),
+		"shipping": models.NewMoney(1500),
+		"tax":      models.NewMoney(450),
+		"total":    models.NewMoney(4950),
+	}
+	got := map[string]models.Money{
+		"subtotal": quote.Subtotal,
+		"discount": quote.Discount,
+		"shipping": quote.Shipping,
+		"tax":      quote.Tax,
+		"total":    quote.Total,
+	}
+	for name, amount := range want {
+		if got[name].Cmp(amount) != 0 {
+			t.Errorf("%s = %s, want %s", name, got[name], amount)
+		}
+	}
+	if quote.ShippingMethod != "express" || quote.TaxRegion != "Flat" {
+		t.Errorf("method = %q, region = %q, want express and Flat", quote.ShippingMethod, quote.TaxRegion)
+	}
+
+	types := make([]string, len(quote.Lines))
+	for i, line := range quote.Lines {
+		types[i] = line.Type
+	}
+	wantTypes := []string{LineItem, LineDiscount, LineShipping, LineTax}
+	if len(types) != len(wantTypes) {
+		t.Fatalf("line types = %v, want %v", types, wantTypes)
+	}
+	for i := range wantTypes {
+		if types[i] != wantTypes[i] {
+			t.Errorf("line types = %v, want %v", types, wantTypes)
+			break
+		}
+	}
+}
+
+func TestCalculateWithoutDiscountOrTax(t *testing.T) {
+	useCalculators(t, NoTax{}, FreeShipping{})
+
+	quote, err := Calculate(nil, Request{Lines: []Line{{Name: "Lamp", Quantity: 3, Price: models.NewMoney(1250)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Total.Cmp(models.NewMoney(3750)) != 0 || !quote.Discount.IsZero() || !quote.Tax.IsZero() {
+		t.Errorf("quote = %+v, want a total of 37.50 with no discount or tax", quote)
+	}
+	// Neither a discount nor an unmatched tax region gets a line
+	if len(quote.Lines) != 2 || quote.Lines[1].Type != LineShipping {
+		t.Errorf("lines = %+v, want the item and free shipping", quote.Lines)
+	}
+}
+
+func TestCalculateRejectsUnavailableShipping(t *testing.T) {
+	useCalculators(t, NoTax{}, fixedShipping{})
+
+	_, err := Calculate(nil, Request{Lines: []Line{{Quantity: 1, Price: models.NewMoney(100)}}})
+	if !errors.Is(err, ErrShippingUnavailable) {
+		t.Errorf("error = %v, want ErrShippingUnavailable", err)
+	}
+}
+
+func TestInitSelectsCalculators(t *testing.T) {
+	useCalculators(t, Tax, Shipping)
+
+	t.Setenv("TAX_CALCULATOR", "none")
+	t.Setenv("SHIPPING_CALCULATOR", "free")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Tax.(NoTax); !ok {
+		t.Errorf("Tax = %T, want NoTax", Tax)
+	}
+	if _, ok := Shipping.(FreeShipping); !ok {
+		t.Errorf("Shipping = %T, want FreeShipping", Shipping)
+	}
+
+	t.Setenv("TAX_CALCULATOR", "vat")
+	if err := Init(); err == nil {
+		t.Error("Init accepted an unknown tax calculator")
+	}
+}
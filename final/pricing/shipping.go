@@ -0,0 +1,64 @@
+package pricing
+
+import (
+	"final/models"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// TieredShipping prices the active ShippingMethods by their weight or price tiers
+type TieredShipping struct{}
+
+func (TieredShipping) Options(db *gorm.DB, req Request) ([]ShippingOption, error) {
+	var methods []models.ShippingMethod
+	if err := db.Preload("Tiers").Where("active = true").Order("code").Find(&methods).Error; err != nil {
+		return nil, err
+	}
+
+	discounted := req.Subtotal().Sub(req.Discount)
+	weight := req.WeightGrams()
+	options := make([]ShippingOption, 0, len(methods))
+	for _, method := range methods {
+		cost, ok := tierCost(method, discounted, weight)
+		if !ok {
+			continue
+		}
+		if method.FreeThreshold != nil && discounted.Cmp(*method.FreeThreshold) >= 0 {
+			cost = models.NewMoney(0)
+		}
+		options = append(options, ShippingOption{Code: method.Code, Name: method.Name, Cost: cost})
+	}
+
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Cost.Cmp(options[j].Cost) < 0 })
+	return options, nil
+}
+
+// tierCost returns the cost of the highest tier the cart reaches
+func tierCost(method models.ShippingMethod, subtotal models.Money, weightGrams int) (models.Money, bool) {
+	var best *models.ShippingTier
+	for i := range method.Tiers {
+		tier := &method.Tiers[i]
+		switch method.Basis {
+		case models.ShippingByWeight:
+			if weightGrams >= tier.MinWeightGrams && (best == nil || tier.MinWeightGrams > best.MinWeightGrams) {
+				best = tier
+			}
+		case models.ShippingByPrice:
+			if subtotal.Cmp(tier.MinSubtotal) >= 0 && (best == nil || tier.MinSubtotal.Cmp(best.MinSubtotal) > 0) {
+				best = tier
+			}
+		}
+	}
+	if best == nil {
+		return models.Money{}, false
+	}
+	return best.Cost, true
+}
+
+// FreeShipping ships every cart for free
+type FreeShipping struct{}
+
+func (FreeShipping) Options(db *gorm.DB, req Request) ([]ShippingOption, error) {
+	return []ShippingOption{{Code: "free", Name: "Free shipping", Cost: models.NewMoney(0)}}, nil
+}
@@ -0,0 +1,57 @@
+package pricing
+
+import (
+	"errors"
+	"final/models"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RegionTax applies the most specific TaxRate matching the shipping address: a
+// rate for the ZIP code prefix beats one for the state, which beats the country's
+type RegionTax struct{}
+
+func (RegionTax) Tax(db *gorm.DB, req Request, shipping models.Money) (TaxResult, error) {
+	result := TaxResult{Rate: "0", Amount: models.NewMoney(0)}
+	address := req.Address
+	if address.Country == "" {
+		return result, nil
+	}
+
+	var rate models.TaxRate
+	err := db.Where("country = ?", strings.ToUpper(address.Country)).
+		Where("state = '' OR UPPER(state) = UPPER(?)", strings.TrimSpace(address.State)).
+		Where("? LIKE zip_prefix || '%'", strings.TrimSpace(address.ZipCode)).
+		Order("LENGTH(zip_prefix) DESC, LENGTH(state) DESC").
+		First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	fraction, ok := new(big.Rat).SetString(rate.Rate)
+	if !ok {
+		return result, fmt.Errorf("tax rate %s has an invalid rate %q", rate.TaxRateID, rate.Rate)
+	}
+	taxable := req.Subtotal().Sub(req.Discount)
+	if rate.TaxShipping {
+		taxable = taxable.Add(shipping)
+	}
+
+	result.Region = rate.Name
+	result.Rate = rate.Rate
+	result.Amount = taxable.MulRate(fraction)
+	return result, nil
+}
+
+// NoTax charges no tax, for stores whose prices include it
+type NoTax struct{}
+
+func (NoTax) Tax(db *gorm.DB, req Request, shipping models.Money) (TaxResult, error) {
+	return TaxResult{Rate: "0", Amount: models.NewMoney(0)}, nil
+}
@@ -0,0 +1,213 @@
+// Package promotions validates coupon codes against a cart and computes their
+// discount. The same rules run for the cart preview and, inside the checkout
+// transaction with the coupon locked, for the order itself.
+package promotions
+
+import (
+	"errors"
+	"final/models"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCoupon is returned when a code does not exist or cannot be applied to the cart
+var ErrInvalidCoupon = errors.New("coupon cannot be applied")
+
+// Line is one product line of the cart being discounted
+type Line struct {
+	ProductID  uuid.UUID
+	CategoryID uuid.UUID
+	Quantity   int
+	Price      models.Money
+}
+
+// Quote is the outcome of applying a coupon to a cart
+type Quote struct {
+	Code     string
+	Subtotal models.Money
+	// EligibleSubtotal is the part of the subtotal the coupon's scope covers
+	EligibleSubtotal models.Money
+	Discount         models.Money
+	// LineDiscounts is each line's share of Discount, in the order of the lines
+	LineDiscounts []models.Money
+	Total         models.Money
+}
+
+// NormalizeCode returns the stored form of a code; codes are case-insensitive
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Find loads a coupon by code together with its product and category scope
+func Find(db *gorm.DB, code string) (models.Coupon, error) {
+	var coupon models.Coupon
+	err := db.Preload("Products").Preload("Categories").
+		Where("code = ?", NormalizeCode(code)).
+		First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return coupon, fmt.Errorf("%w: unknown code %q", ErrInvalidCoupon, code)
+	}
+	return coupon, err
+}
+
+// Lock locks a coupon for the rest of the transaction and loads it, so that usage
+// limits are checked and updated by one checkout at a time
+func Lock(tx *gorm.DB, code string) (models.Coupon, error) {
+	var locked models.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("coupon_id").
+		Where("code = ?", NormalizeCode(code)).
+		First(&locked).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return locked, fmt.Errorf("%w: unknown code %q", ErrInvalidCoupon, code)
+	}
+	if err != nil {
+		return locked, err
+	}
+	return Find(tx, code)
+}
+
+// Apply checks that the coupon can be used by the user on the lines at the given
+// time and computes the discount. Percentage discounts are rounded here, once.
+func Apply(db *gorm.DB, coupon models.Coupon, userID uuid.UUID, lines []Line, now time.Time) (Quote, error) {
+	quote := Quote{Code: coupon.Code, Subtotal: models.NewMoney(0), EligibleSubtotal: models.NewMoney(0)}
+
+	switch {
+	case !coupon.Active:
+		return quote, fmt.Errorf("%w: coupon is not active", ErrInvalidCoupon)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return quote, fmt.Errorf("%w: coupon is not valid yet", ErrInvalidCoupon)
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return quote, fmt.Errorf("%w: coupon has expired", ErrInvalidCoupon)
+	case coupon.UsageLimit != nil && coupon.UsageCount >= *coupon.UsageLimit:
+		return quote, fmt.Errorf("%w: coupon has been used up", ErrInvalidCoupon)
+	}
+
+	if coupon.PerUserLimit != nil {
+		var used int64
+		if err := db.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", coupon.CouponID, userID).
+			Count(&used).Error; err != nil {
+			return quote, err
+		}
+		if used >= int64(*coupon.PerUserLimit) {
+			return quote, fmt.Errorf("%w: you have already used this coupon", ErrInvalidCoupon)
+		}
+	}
+
+	inScope := scope(coupon)
+	for _, line := range lines {
+		total := line.Price.Mul(line.Quantity)
+		quote.Subtotal = quote.Subtotal.Add(total)
+		if inScope(line) {
+			quote.EligibleSubtotal = quote.EligibleSubtotal.Add(total)
+		}
+	}
+	if quote.Subtotal.Cmp(coupon.MinOrderValue) < 0 {
+		return quote, fmt.Errorf("%w: order must be at least %s", ErrInvalidCoupon, coupon.MinOrderValue)
+	}
+	if !quote.EligibleSubtotal.IsPositive() {
+		return quote, fmt.Errorf("%w: no items in the cart qualify", ErrInvalidCoupon)
+	}
+
+	switch coupon.DiscountType {
+	case models.DiscountPercentage:
+		quote.Discount = quote.EligibleSubtotal.MulRate(big.NewRat(int64(coupon.PercentOff), 100))
+	case models.DiscountFixed:
+		quote.Discount = coupon.AmountOff
+		if quote.Discount.Cmp(quote.EligibleSubtotal) > 0 {
+			quote.Discount = quote.EligibleSubtotal
+		}
+	default:
+		return quote, fmt.Errorf("unknown discount type %q", coupon.DiscountType)
+	}
+	quote.LineDiscounts = allocate(quote.Discount, lines, inScope, quote.EligibleSubtotal)
+	quote.Total = quote.Subtotal.Sub(quote.Discount)
+	return quote, nil
+}
+
+// allocate splits a discount over the lines in scope in proportion to their totals,
+// rounding down. The last line in scope takes the remainder, so the shares add up
+// to the discount exactly.
+func allocate(discount models.Money, lines []Line, inScope func(Line) bool, eligible models.Money) []models.Money {
+	shares := make([]models.Money, len(lines))
+	last := -1
+	for i, line := range lines {
+		shares[i] = models.NewMoney(0)
+		if inScope(line) {
+			last = i
+		}
+	}
+
+	remaining := discount
+	for i, line := range lines {
+		if !inScope(line) || i == last {
+			continue
+		}
+		share := new(big.Int).Mul(big.NewInt(discount.Minor), big.NewInt(line.Price.Mul(line.Quantity).Minor))
+		share.Quo(share, big.NewInt(eligible.Minor))
+		shares[i] = models.NewMoney(share.Int64())
+		remaining = remaining.Sub(shares[i])
+	}
+	if last >= 0 {
+		shares[last] = remaining
+	}
+	return shares
+}
+
+// scope returns a predicate matching the lines a coupon discounts
+func scope(coupon models.Coupon) func(Line) bool {
+	if len(coupon.Products) == 0 && len(coupon.Categories) == 0 {
+		return func(Line) bool { return true }
+	}
+	products := make(map[uuid.UUID]bool, len(coupon.Products))
+	for _, product := range coupon.Products {
+		products[product.ProductID] = true
+	}
+	categories := make(map[uuid.UUID]bool, len(coupon.Categories))
+	for _, category := range coupon.Categories {
+		categories[category.CategoryID] = true
+	}
+	return func(line Line) bool {
+		return products[line.ProductID] || categories[line.CategoryID]
+	}
+}
+
+// Redeem records the coupon's use by an order; the coupon must be locked
+func Redeem(tx *gorm.DB, coupon models.Coupon, userID, orderID uuid.UUID, discount models.Money) error {
+	if err := tx.Create(&models.CouponRedemption{
+		CouponID: coupon.CouponID,
+		UserID:   userID,
+		OrderID:  orderID,
+		Discount: discount,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Coupon{}).
+		Where("coupon_id = ?", coupon.CouponID).
+		Update("usage_count", gorm.Expr("usage_count + 1")).Error
+}
+
+// Release gives back the coupon use of a cancelled order, if it had one
+func Release(tx *gorm.DB, orderID uuid.UUID) error {
+	var redemption models.CouponRedemption
+	err := tx.Where("order_id = ?", orderID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Coupon{}).
+		Where("coupon_id = ?", redemption.CouponID).
+		Update("usage_count", gorm.Expr("usage_count - 1")).Error
+}
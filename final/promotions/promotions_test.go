@@ -0,0 +1,153 @@
+package promotions
+
+import (
+	"errors"
+	"final/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// percentOff returns an active, unscoped percentage coupon without limits
+func percentOff(percent int) models.Coupon {
+	return models.Coupon{
+		Code:          "SAVE",
+		DiscountType:  models.DiscountPercentage,
+		PercentOff:    percent,
+		AmountOff:     models.NewMoney(0),
+		MinOrderValue: models.NewMoney(0),
+		Active:        true,
+	}
+}
+
+func TestApplyRejectsIneligibleCoupons(t *testing.T) {
+	lines := []Line{{ProductID: uuid.New(), CategoryID: uuid.New(), Quantity: 2, Price: models.NewMoney(1000)}}
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+	limit := 3
+
+	tests := map[string]func(*models.Coupon){
+		"inactive":    func(c *models.Coupon) { c.Active = false },
+		"not started": func(c *models.Coupon) { c.StartsAt = &later },
+		"expired":     func(c *models.Coupon) { c.EndsAt = &earlier },
+		"ends now":    func(c *models.Coupon) { c.EndsAt = &now },
+		"used up":     func(c *models.Coupon) { c.UsageLimit, c.UsageCount = &limit, 3 },
+		"below minimum order": func(c *models.Coupon) {
+			c.MinOrderValue = models.NewMoney(2001)
+		},
+		"no items in scope": func(c *models.Coupon) {
+			c.Categories = []models.Category{{CategoryID: uuid.New()}}
+		},
+	}
+	for name, change := range tests {
+		coupon := percentOff(10)
+		change(&coupon)
+		if _, err := Apply(nil, coupon, uuid.New(), lines, now); !errors.Is(err, ErrInvalidCoupon) {
+			t.Errorf("%s: error = %v, want ErrInvalidCoupon", name, err)
+		}
+	}
+}
+
+func TestApplyAcceptsCouponsWithinTheirLimits(t *testing.T) {
+	lines := []Line{{Quantity: 2, Price: models.NewMoney(1000)}}
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+	limit := 3
+
+	coupon := percentOff(10)
+	coupon.StartsAt, coupon.EndsAt = &now, &later
+	coupon.UsageLimit, coupon.UsageCount = &limit, 2
+	coupon.MinOrderValue = models.NewMoney(2000)
+	if _, err := Apply(nil, coupon, uuid.New(), lines, now); err != nil {
+		t.Errorf("coupon starting now: %v", err)
+	}
+
+	coupon.StartsAt = &earlier
+	if _, err := Apply(nil, coupon, uuid.New(), lines, now); err != nil {
+		t.Errorf("coupon within its dates: %v", err)
+	}
+}
+
+func TestApplyPercentageDiscountsOnlyLinesInScope(t *testing.T) {
+	product, category := uuid.New(), uuid.New()
+	lines := []Line{
+		{ProductID: product, CategoryID: uuid.New(), Quantity: 1, Price: models.NewMoney(1999)},
+		{ProductID: uuid.New(), CategoryID: category, Quantity: 3, Price: models.NewMoney(500)},
+		{ProductID: uuid.New(), CategoryID: uuid.New(), Quantity: 1, Price: models.NewMoney(10000)},
+	}
+	coupon := percentOff(15)
+	coupon.Products = []models.Product{{ProductID: product}}
+	coupon.Categories = []models.Category{{CategoryID: category}}
+
+	quote, err := Apply(nil, coupon, uuid.New(), lines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 15% of 34.99 is 5.2485, rounded to 5.25
+	if quote.Subtotal.Minor != 13499 || quote.EligibleSubtotal.Minor != 3499 {
+		t.Errorf("subtotal = %d, eligible = %d, want 13499 and 3499", quote.Subtotal.Minor, quote.EligibleSubtotal.Minor)
+	}
+	if quote.Discount.Minor != 525 || quote.Total.Minor != 12974 {
+		t.Errorf("discount = %d, total = %d, want 525 and 12974", quote.Discount.Minor, quote.Total.Minor)
+	}
+	if got := quote.LineDiscounts; got[0].Minor+got[1].Minor != 525 || !got[2].IsZero() {
+		t.Errorf("line discounts = %v, want 5.25 over the first two lines", got)
+	}
+}
+
+func TestApplyFixedDiscountIsCappedAtEligibleSubtotal(t *testing.T) {
+	category := uuid.New()
+	lines := []Line{
+		{CategoryID: category, Quantity: 1, Price: models.NewMoney(800)},
+		{CategoryID: uuid.New(), Quantity: 1, Price: models.NewMoney(5000)},
+	}
+	coupon := percentOff(0)
+	coupon.DiscountType = models.DiscountFixed
+	coupon.AmountOff = models.NewMoney(1500)
+	coupon.Categories = []models.Category{{CategoryID: category}}
+
+	quote, err := Apply(nil, coupon, uuid.New(), lines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Discount.Minor != 800 || quote.Total.Minor != 5000 {
+		t.Errorf("discount = %d, total = %d, want 800 and 5000", quote.Discount.Minor, quote.Total.Minor)
+	}
+
+	coupon.Categories = nil
+	quote, err = Apply(nil, coupon, uuid.New(), lines, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Discount.Minor != 1500 || quote.Total.Minor != 4300 {
+		t.Errorf("discount = %d, total = %d, want 1500 and 4300", quote.Discount.Minor, quote.Total.Minor)
+	}
+}
+
+func TestAllocateSplitsDiscountOverLinesInScope(t *testing.T) {
+	inScopeCategory := uuid.New()
+	lines := []Line{
+		{CategoryID: inScopeCategory, Quantity: 1, Price: models.NewMoney(1000)},
+		{CategoryID: uuid.New(), Quantity: 1, Price: models.NewMoney(5000)},
+		{CategoryID: inScopeCategory, Quantity: 2, Price: models.NewMoney(1000)},
+	}
+	inScope := func(line Line) bool { return line.CategoryID == inScopeCategory }
+
+	// 10.00 off 30.00 eligible: thirds round down, the last line takes the remainder
+	shares := allocate(models.NewMoney(1000), lines, inScope, models.NewMoney(3000))
+	want := []int64{333, 0, 667}
+	for i, share := range shares {
+		if share.Minor != want[i] {
+			t.Errorf("share %d = %d, want %d", i, share.Minor, want[i])
+		}
+	}
+}
+
+func TestAllocateWithoutDiscount(t *testing.T) {
+	lines := []Line{{Quantity: 1, Price: models.NewMoney(1000)}}
+	shares := allocate(models.NewMoney(0), lines, func(Line) bool { return true }, models.NewMoney(1000))
+	if len(shares) != 1 || !shares[0].IsZero() {
+		t.Errorf("shares = %v, want one zero share", shares)
+	}
+}
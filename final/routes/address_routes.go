@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAddressRoutes(router *gin.Engine) {
+	// The address book always belongs to the authenticated user
+	addressGroup := router.Group("/addresses", middlewares.AuthMiddleware())
+	{
+		addressGroup.GET("/", controllers.GetAddresses)        // List saved addresses
+		addressGroup.POST("/", controllers.CreateAddress)      // Add an address
+		addressGroup.PUT("/:id", controllers.UpdateAddress)    // Edit an address or its default flags
+		addressGroup.DELETE("/:id", controllers.DeleteAddress) // Remove an address
+	}
+}
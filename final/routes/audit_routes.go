@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAuditRoutes(router *gin.Engine) {
+	auditGroup := router.Group("/audit", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionAuditRead))
+	{
+		auditGroup.GET("", controllers.GetAuditLogs) // Search the audit log
+	}
+}
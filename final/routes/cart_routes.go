@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCartRoutes(router *gin.Engine) {
+	// The cart always belongs to the authenticated user
+	cartGroup := router.Group("/cart", middlewares.AuthMiddleware())
+	{
+		cartGroup.GET("/", controllers.GetCart)                          // View the cart with subtotal
+		cartGroup.POST("/items", controllers.AddCartItem)                // Add a product or increase its quantity
+		cartGroup.PUT("/items/:id", controllers.UpdateCartItem)          // Change the quantity of a line
+		cartGroup.DELETE("/items/:id", controllers.RemoveCartItem)       // Remove a line
+		cartGroup.POST("/reserve", controllers.ReserveCart)              // Hold the cart's stock while checking out
+		cartGroup.DELETE("/reserve", controllers.ReleaseCartReservation) // Give up the held stock
+		cartGroup.POST("/coupon", controllers.ApplyCartCoupon)           // Apply a coupon code and preview the discount
+		cartGroup.DELETE("/coupon", controllers.RemoveCartCoupon)        // Remove the coupon code
+		cartGroup.POST("/quote", controllers.GetCartQuote)               // Price the cart with discount, shipping and tax
+	}
+}
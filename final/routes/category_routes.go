@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCategoryRoutes(router *gin.Engine) {
+	// Anyone can browse categories
+	categoryGroup := router.Group("/categories")
+	{
+		categoryGroup.GET("/", controllers.GetCategories)                   // List categories
+		categoryGroup.GET("/:id", controllers.GetCategory)                  // View a category
+		categoryGroup.GET("/:id/products", controllers.GetCategoryProducts) // List the products of a category
+
+		// Staff routes
+		adminGroup := categoryGroup.Group("/", middlewares.AuthMiddleware())
+		adminGroup.Use(middlewares.RequirePermission(models.PermissionCategoryWrite)) // Restrict these routes to roles that can edit categories
+		{
+			adminGroup.POST("/", controllers.CreateCategory)      // Admin can create a category
+			adminGroup.PUT("/:id", controllers.UpdateCategory)    // Admin can update a category
+			adminGroup.DELETE("/:id", controllers.DeleteCategory) // Admin can delete a category
+		}
+	}
+}
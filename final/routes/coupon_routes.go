@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCouponRoutes(router *gin.Engine) {
+	couponGroup := router.Group("/coupons", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionPromotionManage))
+	{
+		couponGroup.GET("/", controllers.GetCoupons)         // List coupons
+		couponGroup.GET("/:id", controllers.GetCoupon)       // View a coupon
+		couponGroup.POST("/", controllers.CreateCoupon)      // Create a coupon
+		couponGroup.PUT("/:id", controllers.UpdateCoupon)    // Replace a coupon's rules and scope
+		couponGroup.DELETE("/:id", controllers.DeleteCoupon) // Delete an unused coupon
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterInventoryRoutes(router *gin.Engine) {
+	inventoryGroup := router.Group("/inventory", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionInventoryManage))
+	{
+		inventoryGroup.POST("/products/:id/adjustments", controllers.AdjustStock)    // Restock, write off or correct a product's stock
+		inventoryGroup.GET("/products/:id/movements", controllers.GetStockMovements) // View a product's stock ledger
+	}
+}
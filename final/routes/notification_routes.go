@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterNotificationRoutes(router *gin.Engine) {
+	// Notifications always belong to the authenticated user
+	notificationGroup := router.Group("/notifications", middlewares.AuthMiddleware())
+	{
+		notificationGroup.GET("", controllers.GetNotifications)                  // List notifications
+		notificationGroup.PUT("/read-all", controllers.MarkAllNotificationsRead) // Mark every notification as read
+		notificationGroup.PUT("/:id/read", controllers.MarkNotificationRead)     // Mark one notification as read
+	}
+}
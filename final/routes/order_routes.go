@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOrderRoutes(router *gin.Engine) {
+	// Orders are always scoped to the authenticated user
+	orderGroup := router.Group("/orders", middlewares.AuthMiddleware())
+	{
+		orderGroup.GET("/", controllers.GetOrders)                    // List the user's orders
+		orderGroup.GET("/:id", controllers.GetOrder)                  // View a single order
+		orderGroup.GET("/:id/history", controllers.GetOrderHistory)   // View the status history of an order
+		orderGroup.GET("/:id/payments", controllers.GetOrderPayments) // View payments and refunds of an order
+		orderGroup.POST("/checkout", controllers.Checkout)            // Turn the cart into an order
+		orderGroup.POST("/:id/pay", controllers.PayOrder)             // Pay for a pending order
+		orderGroup.PUT("/:id/cancel", controllers.CancelOrder)        // Cancel a pending order
+
+		// Staff routes
+		orderGroup.PUT("/:id/status", middlewares.RequirePermission(models.PermissionOrderManage), controllers.UpdateOrderStatus) // Staff can advance an order
+		orderGroup.POST("/:id/refund", middlewares.RequirePermission(models.PermissionOrderRefund), controllers.RefundPayment)    // Staff can refund a payment
+	}
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterPricingRoutes(router *gin.Engine) {
+	// Customers can see the shipping methods on offer
+	router.GET("/shipping-methods", controllers.GetShippingMethods)
+
+	shippingGroup := router.Group("/shipping-methods", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionPricingManage))
+	{
+		shippingGroup.GET("/all", controllers.GetAllShippingMethods)   // List every method, including inactive ones
+		shippingGroup.POST("/", controllers.CreateShippingMethod)      // Create a method with its tiers
+		shippingGroup.PUT("/:id", controllers.UpdateShippingMethod)    // Replace a method and its tiers
+		shippingGroup.DELETE("/:id", controllers.DeleteShippingMethod) // Delete a method
+	}
+
+	taxGroup := router.Group("/tax-rates", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionPricingManage))
+	{
+		taxGroup.GET("/", controllers.GetTaxRates)         // List tax rates by region
+		taxGroup.POST("/", controllers.CreateTaxRate)      // Create a rate for a country, state or ZIP prefix
+		taxGroup.PUT("/:id", controllers.UpdateTaxRate)    // Update a rate
+		taxGroup.DELETE("/:id", controllers.DeleteTaxRate) // Delete a rate
+	}
+}
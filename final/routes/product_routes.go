@@ -3,6 +3,7 @@ package routes
 import (
 	"final/controllers"
 	"final/middlewares"
+	"final/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,16 +12,25 @@ func RegisterProductRoutes(router *gin.Engine) {
 	// Protect product routes with AuthMiddleware
 	productGroup := router.Group("/products", middlewares.AuthMiddleware())
 	{
-		productGroup.GET("/", controllers.GetProducts)   // Authenticated users can view products
-		productGroup.GET("/:id", controllers.GetProduct) // Authenticated users can view product details
+		productGroup.GET("/", controllers.GetProducts)                    // Authenticated users can view products
+		productGroup.GET("/search", controllers.SearchProducts)           // Authenticated users can search products
+		productGroup.GET("/:id", controllers.GetProduct)                  // Authenticated users can view product details
+		productGroup.GET("/:id/variants", controllers.GetProductVariants) // Authenticated users can view a product's variants and options
 
-		// Admin-only routes
+		// Staff routes
 		adminGroup := productGroup.Group("/")
-		adminGroup.Use(middlewares.RoleMiddleware("admin")) // Restrict these routes to admin users
+		adminGroup.Use(middlewares.RequirePermission(models.PermissionProductWrite)) // Restrict these routes to roles that can edit products
 		{
 			adminGroup.POST("/", controllers.CreateProduct)      // Admin can create a product
 			adminGroup.PUT("/:id", controllers.UpdateProduct)    // Admin can update a product
 			adminGroup.DELETE("/:id", controllers.DeleteProduct) // Admin can delete a product
+
+			adminGroup.POST("/:id/images", controllers.UploadProductImage)             // Admin can upload a product image
+			adminGroup.DELETE("/:id/images/:image_id", controllers.DeleteProductImage) // Admin can delete a product image
+
+			adminGroup.POST("/:id/variants", controllers.CreateProductVariant)               // Admin can add a variant with its initial stock
+			adminGroup.PUT("/:id/variants/:variant_id", controllers.UpdateProductVariant)    // Admin can update a variant's SKU, options, price or weight
+			adminGroup.DELETE("/:id/variants/:variant_id", controllers.DeleteProductVariant) // Admin can delete a variant without stock
 		}
 	}
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReportRoutes(router *gin.Engine) {
+	reportGroup := router.Group("/reports", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionReportRead))
+	{
+		reportGroup.GET("/sales", controllers.GetSalesReport)              // Sales grouped by product, category or period
+		reportGroup.GET("/top-products", controllers.GetTopProductsReport) // Best-selling products
+		reportGroup.GET("/low-stock", controllers.GetLowStockReport)       // Products at or below their low-stock threshold
+	}
+}
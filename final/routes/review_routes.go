@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReviewRoutes(router *gin.Engine) {
+	// Anyone can read reviews
+	router.GET("/products/:id/reviews", controllers.GetProductReviews)
+
+	// Writing reviews requires an authenticated user
+	reviewGroup := router.Group("/", middlewares.AuthMiddleware())
+	{
+		reviewGroup.POST("/products/:id/reviews", controllers.CreateReview) // Review a delivered product
+		reviewGroup.PUT("/reviews/:id", controllers.UpdateReview)           // Edit own review
+		reviewGroup.DELETE("/reviews/:id", controllers.DeleteReview)        // Delete own review
+	}
+}
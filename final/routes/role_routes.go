@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+	"final/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoleRoutes(router *gin.Engine) {
+	// Managing roles is itself a permission
+	roleGroup := router.Group("/", middlewares.AuthMiddleware(), middlewares.RequirePermission(models.PermissionRoleManage))
+	{
+		roleGroup.GET("/permissions", controllers.GetPermissions)                            // List all permissions
+		roleGroup.GET("/roles", controllers.GetRoles)                                        // List roles with their permissions
+		roleGroup.POST("/roles", controllers.CreateRole)                                     // Create a role
+		roleGroup.POST("/roles/:id/permissions", controllers.GrantPermission)                // Grant a permission to a role
+		roleGroup.DELETE("/roles/:id/permissions/:permission", controllers.RevokePermission) // Revoke a permission from a role
+	}
+}
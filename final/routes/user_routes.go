@@ -2,6 +2,8 @@ package routes
 
 import (
 	"final/controllers"
+	"final/middlewares"
+	"final/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,5 +13,20 @@ func RegisterUserRoutes(router *gin.Engine) {
 	{
 		userGroup.POST("/register", controllers.Register)
 		userGroup.POST("/login", controllers.Login)
+		userGroup.POST("/refresh", controllers.RefreshSession)
+		userGroup.POST("/logout", middlewares.AuthMiddleware(), controllers.Logout)
+		userGroup.POST("/logout-all", middlewares.AuthMiddleware(), controllers.LogoutAll)
+
+		// Staff routes
+		adminGroup := userGroup.Group("/", middlewares.AuthMiddleware())
+		adminGroup.Use(middlewares.RequirePermission(models.PermissionUserManage)) // Restrict these routes to roles that can manage users
+		{
+			adminGroup.PUT("/:id/role", controllers.UpdateUserRole)    // Admin can grant a role
+			adminGroup.DELETE("/:id/role", controllers.RevokeUserRole) // Admin can revoke a role
+		}
 	}
 }
+
+func RegisterWellKnownRoutes(router *gin.Engine) {
+	router.GET("/.well-known/jwks.json", controllers.GetJWKS) // Public keys for verifying access tokens
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"final/controllers"
+	"final/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterWishlistRoutes(router *gin.Engine) {
+	// Anyone with the link can view a shared wishlist
+	router.GET("/wishlists/shared/:token", controllers.GetSharedWishlist)
+
+	// Wishlists always belong to the authenticated user
+	wishlistGroup := router.Group("/wishlists", middlewares.AuthMiddleware())
+	{
+		wishlistGroup.GET("/", controllers.GetWishlists)                                           // List wishlists with their items
+		wishlistGroup.POST("/", controllers.CreateWishlist)                                        // Create a named wishlist
+		wishlistGroup.GET("/:id", controllers.GetWishlist)                                         // View a wishlist
+		wishlistGroup.PUT("/:id", controllers.UpdateWishlist)                                      // Rename a wishlist
+		wishlistGroup.DELETE("/:id", controllers.DeleteWishlist)                                   // Delete a wishlist
+		wishlistGroup.POST("/:id/items", controllers.AddWishlistItem)                              // Add a product, optionally pinned to a variant
+		wishlistGroup.DELETE("/:id/items/:item_id", controllers.RemoveWishlistItem)                // Remove an item
+		wishlistGroup.POST("/:id/items/:item_id/move-to-cart", controllers.MoveWishlistItemToCart) // Move an item into the cart
+		wishlistGroup.POST("/:id/share", controllers.ShareWishlist)                                // Get a public link to the wishlist
+		wishlistGroup.DELETE("/:id/share", controllers.UnshareWishlist)                            // Revoke the public link
+	}
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "http://localhost:8080/uploads/")
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "products/1/photo.jpg", strings.NewReader("jpeg bytes"), 10, "image/jpeg"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, "products", "1", "photo.jpg"))
+	if err != nil || string(stored) != "jpeg bytes" {
+		t.Fatalf("stored file = %q, %v", stored, err)
+	}
+	if got, want := s.URL("products/1/photo.jpg"), "http://localhost:8080/uploads/products/1/photo.jpg"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	if err := s.Delete(ctx, "products/1/photo.jpg"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "products", "1", "photo.jpg")); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "products/1/photo.jpg"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+}
+
+func TestLocalKeysStayInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(filepath.Join(dir, "uploads"), "/uploads")
+
+	if err := s.Put(context.Background(), "../../escaped.txt", strings.NewReader("x"), 1, "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "escaped.txt")); err != nil {
+		t.Errorf("file was not kept inside the storage directory: %v", err)
+	}
+
+	if _, err := s.path("/"); err == nil {
+		t.Error("the storage root was accepted as a key")
+	}
+}
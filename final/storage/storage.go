@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Storage stores uploaded files under keys such as "products/<id>/<file>"
+type Storage interface {
+	// Put writes size bytes from body under key, replacing any existing object
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Delete removes the object under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the object under key
+	URL(key string) string
+}
+
+// Default is the storage backend used by the API
+var Default Storage
+
+// Init configures Default from the STORAGE_BACKEND environment variable ("local" or "s3")
+func Init() error {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		Default = NewLocalStorage(getenv("STORAGE_LOCAL_DIR", "./uploads"), getenv("STORAGE_PUBLIC_URL", "/uploads"))
+	case "s3":
+		s3, err := NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    getenv("S3_REGION", "us-east-1"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		})
+		if err != nil {
+			return err
+		}
+		Default = s3
+	default:
+		return fmt.Errorf("unknown storage backend %q", backend)
+	}
+	return nil
+}
+
+// getenv returns the environment variable or a fallback when it is unset
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
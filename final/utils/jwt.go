@@ -1,41 +1,82 @@
 package utils
 
 import (
+	"final/config"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret = []byte("your_secret_key")
+// AccessTokenTTL is how long an access token stays valid; clients renew it with a refresh token
+const AccessTokenTTL = 15 * time.Minute
 
-// GenerateJWT generates a JWT token for a user
-func GenerateJWT(userID string, role string) (string, error) {
+// signingMethods maps configured algorithms to their JWT signing methods
+var signingMethods = map[string]jwt.SigningMethod{
+	config.JWTAlgHS256: jwt.SigningMethodHS256,
+	config.JWTAlgRS256: jwt.SigningMethodRS256,
+	config.JWTAlgEdDSA: jwt.SigningMethodEdDSA,
+}
+
+// GenerateJWT generates a short-lived access token for a user's session
+func GenerateJWT(userID string, role string, sessionID string) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"role":    role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(), // Token expiration
+		"sid":     sessionID,
+		"iss":     config.JWT.Issuer,
+		"aud":     config.JWT.Audience,
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
+		"exp":     now.Add(AccessTokenTTL).Unix(), // Token expiration
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	key := config.JWT.SigningKey
+	token := jwt.NewWithClaims(signingMethods[key.Algorithm], claims)
+	token.Header["kid"] = key.ID
+
+	if key.Algorithm == config.JWTAlgHS256 {
+		return token.SignedString(key.Secret)
+	}
+	return token.SignedString(key.PrivateKey)
 }
 
 // ValidateJWT validates a JWT token and extracts claims
 func ValidateJWT(tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// Pick the key named by the kid header and require the algorithm configured for it
+		kid, _ := token.Header["kid"].(string)
+		key, ok := config.JWT.Keys[kid]
+		if !ok {
+			return nil, jwt.NewValidationError("unknown signing key", jwt.ValidationErrorSignatureInvalid)
+		}
+		if token.Method.Alg() != key.Algorithm {
 			return nil, jwt.NewValidationError("invalid signing method", jwt.ValidationErrorSignatureInvalid)
 		}
-		return jwtSecret, nil
+		if key.Algorithm == config.JWTAlgHS256 {
+			return key.Secret, nil
+		}
+		return key.PublicKey, nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+	// exp, nbf and iat are checked by Parse; issuer and audience are checked here
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.NewValidationError("invalid token", jwt.ValidationErrorSignatureInvalid)
+	}
+	if !claims.VerifyIssuer(config.JWT.Issuer, true) {
+		return nil, jwt.NewValidationError("invalid issuer", jwt.ValidationErrorIssuer)
+	}
+	if !claims.VerifyAudience(config.JWT.Audience, true) {
+		return nil, jwt.NewValidationError("invalid audience", jwt.ValidationErrorAudience)
+	}
+	if _, ok := claims["nbf"]; !ok {
+		return nil, jwt.NewValidationError("missing nbf claim", jwt.ValidationErrorNotValidYet)
 	}
 
-	return nil, jwt.NewValidationError("invalid token", jwt.ValidationErrorSignatureInvalid)
+	return claims, nil
 }
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail scales img down so that its longest side is at most maxSize pixels,
+// averaging the source pixels covered by each thumbnail pixel. Smaller images are
+// returned unchanged.
+func Thumbnail(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+
+	thumbWidth, thumbHeight := maxSize, maxSize
+	if width > height {
+		thumbHeight = max(1, height*maxSize/width)
+	} else {
+		thumbWidth = max(1, width*maxSize/height)
+	}
+
+	// Work on RGBA pixels directly instead of going through the color interfaces
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for ty := 0; ty < thumbHeight; ty++ {
+		y0, y1 := ty*height/thumbHeight, max((ty+1)*height/thumbHeight, ty*height/thumbHeight+1)
+		for tx := 0; tx < thumbWidth; tx++ {
+			x0, x1 := tx*width/thumbWidth, max((tx+1)*width/thumbWidth, tx*width/thumbWidth+1)
+
+			var r, g, b, a, count int
+			for y := y0; y < y1; y++ {
+				offset := src.PixOffset(x0, y)
+				for x := x0; x < x1; x++ {
+					r += int(src.Pix[offset])
+					g += int(src.Pix[offset+1])
+					b += int(src.Pix[offset+2])
+					a += int(src.Pix[offset+3])
+					offset += 4
+					count++
+				}
+			}
+
+			offset := dst.PixOffset(tx, ty)
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
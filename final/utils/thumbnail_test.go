@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnailKeepsAspectRatio(t *testing.T) {
+	tests := []struct {
+		width, height, wantWidth, wantHeight int
+	}{
+		{800, 400, 200, 100},
+		{400, 800, 100, 200},
+		{1000, 3, 200, 1},
+	}
+	for _, tt := range tests {
+		thumb := Thumbnail(image.NewRGBA(image.Rect(0, 0, tt.width, tt.height)), 200)
+		if got := thumb.Bounds(); got.Dx() != tt.wantWidth || got.Dy() != tt.wantHeight {
+			t.Errorf("Thumbnail of %dx%d is %dx%d, want %dx%d", tt.width, tt.height, got.Dx(), got.Dy(), tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
+
+func TestThumbnailLeavesSmallImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 50, 20))
+	if Thumbnail(img, 200) != image.Image(img) {
+		t.Error("a small image was resized")
+	}
+}
+
+func TestThumbnailAveragesPixels(t *testing.T) {
+	// Alternating black and white columns average to grey
+	img := image.NewRGBA(image.Rect(10, 10, 14, 12))
+	for y := 10; y < 12; y++ {
+		for x := 10; x < 14; x++ {
+			if x%2 == 0 {
+				img.Set(x, y, color.RGBA{255, 255, 255, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+
+	thumb := Thumbnail(img, 2).(*image.RGBA)
+	if got := thumb.RGBAAt(0, 0); got != (color.RGBA{127, 127, 127, 255}) {
+		t.Errorf("thumbnail pixel = %v, want grey", got)
+	}
+}
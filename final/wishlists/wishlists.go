@@ -0,0 +1,84 @@
+// Package wishlists tells users when products on their wishlists become easier to
+// buy: when they come back in stock and when their price drops. Notifications are
+// written in the caller's transaction, like every notification.
+package wishlists
+
+import (
+	"final/models"
+	"final/notifications"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// wishers returns a query for the users with a matching wishlist item of the product
+func wishers(tx *gorm.DB, productID uuid.UUID) *gorm.DB {
+	return tx.Model(&models.WishlistItem{}).
+		Joins("JOIN wishlists ON wishlists.wishlist_id = wishlist_items.wishlist_id").
+		Where("wishlist_items.product_id = ?", productID).
+		Distinct()
+}
+
+// NotifyBackInStock tells users that a variant which was out of stock can be bought
+// again. Users who wished for the variant are told, and so are users who wished for
+// the product as a whole when none of its variants was in stock before.
+func NotifyBackInStock(tx *gorm.DB, product models.Product, variant models.ProductVariant, productStockBefore int) error {
+	query := wishers(tx, product.ProductID)
+	if productStockBefore <= 0 {
+		query = query.Where("wishlist_items.variant_id IS NULL OR wishlist_items.variant_id = ?", variant.VariantID)
+	} else {
+		query = query.Where("wishlist_items.variant_id = ?", variant.VariantID)
+	}
+
+	var userIDs []uuid.UUID
+	if err := query.Pluck("wishlists.user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	return notifications.Notify(tx, notifications.Message{
+		Type:    models.NotificationBackInStock,
+		Title:   fmt.Sprintf("%s is back in stock", variant.Label(product)),
+		Message: "An item on your wishlist can be ordered again",
+		Data: models.JSONMap{
+			"product_id": product.ProductID.String(),
+			"variant_id": variant.VariantID.String(),
+		},
+	}, userIDs...)
+}
+
+// NotifyPriceDrop tells users that a wished product got cheaper. With a variant, its
+// own price changed and users who wished for that variant are told. Without one,
+// the product's base price changed, which reaches users who wished for the product
+// as a whole or for a variant that has no price of its own.
+func NotifyPriceDrop(tx *gorm.DB, product models.Product, variant *models.ProductVariant, before, after models.Money) error {
+	if after.Cmp(before) >= 0 {
+		return nil
+	}
+
+	query := wishers(tx, product.ProductID)
+	title := product.Name
+	data := models.JSONMap{
+		"product_id":     product.ProductID.String(),
+		"price":          after.String(),
+		"previous_price": before.String(),
+	}
+	if variant != nil {
+		query = query.Where("wishlist_items.variant_id = ?", variant.VariantID)
+		title = variant.Label(product)
+		data["variant_id"] = variant.VariantID.String()
+	} else {
+		query = query.Where(`wishlist_items.variant_id IS NULL OR wishlist_items.variant_id IN
+			(SELECT variant_id FROM product_variants WHERE product_id = ? AND price IS NULL)`, product.ProductID)
+	}
+
+	var userIDs []uuid.UUID
+	if err := query.Pluck("wishlists.user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	return notifications.Notify(tx, notifications.Message{
+		Type:    models.NotificationPriceDrop,
+		Title:   fmt.Sprintf("%s dropped in price", title),
+		Message: fmt.Sprintf("Now %s, was %s", after.String(), before.String()),
+		Data:    data,
+	}, userIDs...)
+}